@@ -3,6 +3,6 @@ module github.com/MaiBeng/protoc-gen-postman
 go 1.17
 
 require (
-	github.com/golang/protobuf v1.4.0-rc.2
-	google.golang.org/protobuf v1.20.0
+	github.com/golang/protobuf v1.5.2
+	google.golang.org/protobuf v1.27.1
 )
@@ -293,6 +293,13 @@ type HttpRule struct {
 	// body. NOTE: the referred field must not be a repeated field and must be
 	// present at the top-level of request message type.
 	Body string `protobuf:"bytes,7,opt,name=body,proto3" json:"body,omitempty"`
+	// Optional. The name of the response field whose value is mapped to the HTTP
+	// response body. When omitted, the entire response message will be used
+	// as the HTTP response body.
+	//
+	// NOTE: The referred field must be present at the top-level of the response
+	// message type.
+	ResponseBody string `protobuf:"bytes,12,opt,name=response_body,json=responseBody,proto3" json:"response_body,omitempty"`
 	// Additional HTTP bindings for the selector. Nested bindings must
 	// not contain an `additional_bindings` field themselves (that is,
 	// the nesting may only be one level deep).
@@ -430,6 +437,13 @@ func (m *HttpRule) GetBody() string {
 	return ""
 }
 
+func (m *HttpRule) GetResponseBody() string {
+	if m != nil {
+		return m.ResponseBody
+	}
+	return ""
+}
+
 func (m *HttpRule) GetAdditionalBindings() []*HttpRule {
 	if m != nil {
 		return m.AdditionalBindings
@@ -508,27 +522,29 @@ func init() {
 func init() { proto.RegisterFile("proto/http.proto", fileDescriptor_eeab61fdf26c4aa1) }
 
 var fileDescriptor_eeab61fdf26c4aa1 = []byte{
-	// 352 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x51, 0x3d, 0x6b, 0xe3, 0x30,
-	0x18, 0x3e, 0x27, 0x8e, 0x93, 0xbc, 0x81, 0xe3, 0x4e, 0x17, 0x0e, 0x51, 0x28, 0x84, 0x4c, 0xa1,
-	0x83, 0x0d, 0xe9, 0xd0, 0x21, 0x53, 0x5c, 0x4a, 0xd3, 0x2d, 0x78, 0xec, 0x52, 0x14, 0x5b, 0x28,
-	0x6a, 0x1d, 0x49, 0xd8, 0xaf, 0x87, 0xfe, 0x9d, 0xfe, 0x87, 0xfe, 0xb7, 0x8e, 0x45, 0x1f, 0x69,
-	0x02, 0x85, 0x6e, 0xcf, 0x97, 0xf4, 0x7e, 0xc1, 0x1f, 0xd3, 0x68, 0xd4, 0xd9, 0x1e, 0xd1, 0xa4,
-	0x0e, 0x12, 0x10, 0x5a, 0x8b, 0x9a, 0xa7, 0xcc, 0xc8, 0xf9, 0x12, 0xe2, 0x0d, 0xa2, 0x21, 0x57,
-	0x30, 0x68, 0xba, 0x9a, 0xb7, 0x34, 0x9a, 0xf5, 0x17, 0x93, 0xe5, 0x34, 0x3d, 0x65, 0x52, 0x1b,
-	0x28, 0xba, 0x9a, 0x17, 0x3e, 0x32, 0x7f, 0xef, 0xc1, 0xe8, 0xa8, 0x91, 0x0b, 0x18, 0xb5, 0xbc,
-	0xe6, 0x25, 0xea, 0x86, 0x46, 0xb3, 0x68, 0x31, 0x2e, 0xbe, 0x38, 0x21, 0xd0, 0x17, 0x1c, 0x69,
-	0xcf, 0xca, 0x9b, 0x5f, 0x85, 0x25, 0x56, 0x33, 0x1d, 0xd2, 0xfe, 0x51, 0x33, 0x1d, 0x92, 0x29,
-	0xc4, 0x46, 0xb7, 0x48, 0xe3, 0x20, 0x3a, 0x46, 0x28, 0x24, 0x15, 0xaf, 0x39, 0x72, 0x3a, 0x08,
-	0x7a, 0xe0, 0xe4, 0x3f, 0x0c, 0x0c, 0xc3, 0x72, 0x4f, 0x93, 0x60, 0x78, 0x4a, 0x6e, 0x20, 0x29,
-	0xbb, 0x16, 0xf5, 0x81, 0x8e, 0x66, 0xd1, 0x62, 0xb2, 0xbc, 0x3c, 0x9f, 0xe2, 0xd6, 0x39, 0xb6,
-	0xef, 0x2d, 0x43, 0xe4, 0x8d, 0xb2, 0x1f, 0xfa, 0x38, 0x21, 0x10, 0xef, 0x74, 0xf5, 0x4a, 0x87,
-	0x6e, 0x00, 0x87, 0xc9, 0x1d, 0xfc, 0x63, 0x55, 0x25, 0x51, 0x6a, 0xc5, 0xea, 0xa7, 0x9d, 0x54,
-	0x95, 0x54, 0xa2, 0xa5, 0x93, 0x1f, 0xf6, 0x43, 0x4e, 0x0f, 0xf2, 0x90, 0xcf, 0xc7, 0x30, 0x34,
-	0xbe, 0xde, 0x7c, 0x05, 0x7f, 0xbf, 0x35, 0x61, 0x4b, 0xbf, 0x48, 0x55, 0x85, 0xdd, 0x39, 0x6c,
-	0x35, 0xc3, 0x70, 0xef, 0x17, 0x57, 0x38, 0x9c, 0x3f, 0xc3, 0xef, 0x52, 0x1f, 0xce, 0xca, 0xe6,
-	0x63, 0xf7, 0x8d, 0xbd, 0xe8, 0x36, 0x7a, 0x5c, 0x07, 0x43, 0xe8, 0x9a, 0x29, 0x91, 0xea, 0x46,
-	0x64, 0x82, 0x2b, 0x7f, 0x7a, 0x6f, 0x31, 0x23, 0xdb, 0x8c, 0x19, 0x99, 0x31, 0xa5, 0x34, 0x32,
-	0xdb, 0x66, 0xbb, 0x3a, 0xc3, 0x1f, 0x51, 0xf4, 0xd6, 0x8b, 0xef, 0xd7, 0xdb, 0x87, 0x5d, 0xe2,
-	0xde, 0x5d, 0x7f, 0x06, 0x00, 0x00, 0xff, 0xff, 0xa1, 0xe5, 0xd2, 0xa3, 0x3b, 0x02, 0x00, 0x00,
+	// 370 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x52, 0x3f, 0x6f, 0xe2, 0x30,
+	0x14, 0xbf, 0x40, 0x08, 0xf0, 0xe0, 0x4e, 0x77, 0x3e, 0x74, 0xb2, 0x4e, 0xaa, 0x84, 0xe8, 0x82,
+	0x3a, 0x24, 0x12, 0x1d, 0x3a, 0x30, 0x91, 0xaa, 0x2a, 0xdd, 0x50, 0xc6, 0x2e, 0xc8, 0x24, 0x96,
+	0x71, 0x1b, 0x6c, 0x2b, 0x7e, 0x19, 0xfa, 0x75, 0xfa, 0xb1, 0xfa, 0x49, 0x3a, 0x56, 0x76, 0x42,
+	0x41, 0xaa, 0xd4, 0xed, 0xf7, 0xcf, 0xce, 0xef, 0xbd, 0x18, 0x7e, 0x9b, 0x4a, 0xa3, 0x4e, 0xf6,
+	0x88, 0x26, 0xf6, 0x90, 0x80, 0xd0, 0x5a, 0x94, 0x3c, 0x66, 0x46, 0xce, 0x16, 0x10, 0xae, 0x11,
+	0x0d, 0xb9, 0x82, 0x5e, 0x55, 0x97, 0xdc, 0xd2, 0x60, 0xda, 0x9d, 0x8f, 0x16, 0x93, 0xf8, 0x94,
+	0x89, 0x5d, 0x20, 0xab, 0x4b, 0x9e, 0x35, 0x91, 0xd9, 0x5b, 0x07, 0x06, 0x47, 0x8d, 0xfc, 0x87,
+	0x81, 0xe5, 0x25, 0xcf, 0x51, 0x57, 0x34, 0x98, 0x06, 0xf3, 0x61, 0xf6, 0xc9, 0x09, 0x81, 0xae,
+	0xe0, 0x48, 0x3b, 0x4e, 0x5e, 0xff, 0xc8, 0x1c, 0x71, 0x9a, 0xa9, 0x91, 0x76, 0x8f, 0x9a, 0xa9,
+	0x91, 0x4c, 0x20, 0x34, 0xda, 0x22, 0x0d, 0x5b, 0xd1, 0x33, 0x42, 0x21, 0x2a, 0x78, 0xc9, 0x91,
+	0xd3, 0x5e, 0xab, 0xb7, 0x9c, 0xfc, 0x83, 0x9e, 0x61, 0x98, 0xef, 0x69, 0xd4, 0x1a, 0x0d, 0x25,
+	0x37, 0x10, 0xe5, 0xb5, 0x45, 0x7d, 0xa0, 0x83, 0x69, 0x30, 0x1f, 0x2d, 0x2e, 0xce, 0xa7, 0xb8,
+	0xf5, 0x8e, 0xeb, 0xbd, 0x61, 0x88, 0xbc, 0x52, 0xee, 0xc2, 0x26, 0x4e, 0x08, 0x84, 0x3b, 0x5d,
+	0xbc, 0xd0, 0xbe, 0x1f, 0xc0, 0x63, 0x72, 0x09, 0x3f, 0x2b, 0x6e, 0x8d, 0x56, 0x96, 0x6f, 0xbd,
+	0x39, 0xf6, 0xe6, 0xf8, 0x28, 0xa6, 0x2e, 0x74, 0x07, 0x7f, 0x59, 0x51, 0x48, 0x94, 0x5a, 0xb1,
+	0x72, 0xbb, 0x93, 0xaa, 0x90, 0x4a, 0x58, 0x3a, 0xfa, 0x66, 0x89, 0xe4, 0x74, 0x20, 0x6d, 0xf3,
+	0xe9, 0x10, 0xfa, 0xa6, 0x29, 0x35, 0x5b, 0xc2, 0x9f, 0x2f, 0x4d, 0x5d, 0xbf, 0x67, 0xa9, 0x8a,
+	0x76, 0xc1, 0x1e, 0x3b, 0xcd, 0x30, 0xdc, 0x37, 0xdb, 0xcd, 0x3c, 0x4e, 0x9f, 0xe0, 0x57, 0xae,
+	0x0f, 0x67, 0x9f, 0x4d, 0x87, 0xfe, 0x1a, 0xf7, 0xdb, 0x37, 0xc1, 0xe3, 0xaa, 0x35, 0x84, 0x2e,
+	0x99, 0x12, 0xb1, 0xae, 0x44, 0x22, 0xb8, 0x6a, 0xde, 0x47, 0x63, 0x31, 0x23, 0x6d, 0xc2, 0x8c,
+	0x4c, 0x98, 0x52, 0x1a, 0x99, 0xab, 0x69, 0x97, 0x67, 0xf8, 0x3d, 0x08, 0x5e, 0x3b, 0xe1, 0xfd,
+	0x6a, 0xf3, 0xb0, 0x8b, 0xfc, 0xb9, 0xeb, 0x8f, 0x01, 0x00, 0xa3, 0x2c, 0x7b, 0x78, 0x60, 0x02,
+	0x00, 0x00,
 }
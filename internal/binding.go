@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MaiBeng/protoc-gen-postman/google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Binding is a google.api.http binding resolved against the input message of the method,
+// the postman collection and the OpenAPI document are both rendered from it
+type Binding struct {
+	Rule      *annotations.HttpRule
+	Method    string // request method, eg: GET
+	Path      string // path template, eg: /v1/{name=projects/*}:cancel
+	Template  *PathTemplate
+	Variables []*BindingVariable
+	BodyAll   bool              // body: "*", all the fields not bound to the path are in the body
+	Body      []*protogen.Field // body: "field", the field in the body
+}
+
+// BindingVariable is a path variable of the binding
+type BindingVariable struct {
+	Key     string // field names joined by ".", eg: user.id
+	Segment *PathSegment
+	Fields  []*protogen.Field // fields of the field path, from the input message
+}
+
+// getMethodRules returns the http rules of method, additional_bindings included,
+// methods without the google.api.http option follow the unannotated parameter and return nil when skipped
+func (p *Postman) getMethodRules(method *protogen.Method) ([]*annotations.HttpRule, error) {
+	// 因为我们通过method.Desc.Options() 拿到的数据类型是`interface{}` 类型
+	// 所以这里我们需要对Options，明确指定转换为 *descriptorpb.MethodOptions 类型
+	// 这样子就能拿到我们的MethodOption对象
+	options, ok := method.Desc.Options().(*descriptorpb.MethodOptions)
+	if !ok {
+		return nil, fmt.Errorf("method.Desc.Options err")
+	}
+
+	// PS：重点
+	// 这里我们看到我们借助了一个非protogen下的包的内容
+	// 原因就是，protobuf编译器会把自定义的Option全部指定为Extension，由于并非内置的属性和值
+	// protobuf官方是没办法拿到和你对应的可读的内容的，只能通过拿到经过序列化之后的数据。
+	// 因此，我们这里通过 proto.GetExtension的方法，把刚才annotations.proto单独编译好的 annotations.pb.proto 文件下的 annotations.E_HTTP 加载进来，
+	// 指定了我需要在自定义扩展的MethodOptions中，拿到该Http下里面的value
+	// 也因此，我们可以再经过一次类型转换，就可以拿到了具体的httpRule
+	httpRule, _ := proto.GetExtension(options, annotations.E_Http).(*annotations.HttpRule)
+
+	if httpRule.GetPattern() == nil {
+		// 没有 http 注解
+		switch p.Unannotated {
+		case UNANNOTATED_DEFAULT:
+			return []*annotations.HttpRule{p.getDefaultHttpRule(method)}, nil
+		default:
+			return nil, nil
+		}
+	}
+
+	return append([]*annotations.HttpRule{httpRule}, httpRule.GetAdditionalBindings()...), nil
+}
+
+// getBinding resolves the path variables and the body of httpRule against the input of method
+func (p *Postman) getBinding(method *protogen.Method, httpRule *annotations.HttpRule) (*Binding, error) {
+	requestMethod, urlHost := p.getHttpPattern(httpRule)
+	template, err := parsePathTemplate(urlHost)
+	if err != nil {
+		return nil, err
+	}
+
+	var binding = &Binding{
+		Rule:     httpRule,
+		Method:   requestMethod,
+		Path:     urlHost,
+		Template: template,
+	}
+	for _, segment := range template.Variables {
+		fields, err := p.getFields(method.Input, segment.FieldPath)
+		if err != nil {
+			return nil, fmt.Errorf("path template %q: %v", urlHost, err)
+		}
+
+		var keys []string
+		for _, field := range fields {
+			keys = append(keys, p.fieldName(field))
+		}
+		binding.Variables = append(binding.Variables, &BindingVariable{
+			Key:     strings.Join(keys, "."),
+			Segment: segment,
+			Fields:  fields,
+		})
+	}
+
+	// body: "*" 时所有非 path 字段放在 body 中；body: "field" 时只有该字段放在 body 中，其余字段作为 query；
+	// 未指定 body 时所有非 path 字段都作为 query
+	switch body := httpRule.GetBody(); body {
+	case "":
+	case "*":
+		binding.BodyAll = true
+	default:
+		fields, err := p.getFields(method.Input, []string{body})
+		if err != nil {
+			return nil, fmt.Errorf("body %q: %v", body, err)
+		}
+		binding.Body = fields
+	}
+
+	return binding, nil
+}
+
+// GetPaths returns the segments of the path, variable renders each path variable,
+// verb reports whether the verb follows the variable
+func (b *Binding) GetPaths(variable func(v *BindingVariable, verb bool) string) []string {
+	var paths []string
+	var i int
+	for j, segment := range b.Template.Segments {
+		if !segment.IsVariable() {
+			paths = append(paths, segment.Literal)
+			continue
+		}
+
+		paths = append(paths, variable(b.Variables[i], b.Template.Verb != "" && j == len(b.Template.Segments)-1))
+		i++
+	}
+	if b.Template.Verb != "" && len(paths) > 0 {
+		paths[len(paths)-1] += ":" + b.Template.Verb
+	}
+
+	return paths
+}
+
+// IsBound reports whether the field path of the input message is bound to the path or the body,
+// bound fields are not passed as query params
+func (b *Binding) IsBound(fieldPath []string) bool {
+	if b.BodyAll {
+		return true
+	}
+
+	name := strings.Join(fieldPath, ".")
+	for _, variable := range b.Variables {
+		if strings.Join(variable.Segment.FieldPath, ".") == name {
+			return true
+		}
+	}
+	for _, field := range b.Body {
+		if string(field.Desc.Name()) == name {
+			return true
+		}
+	}
+
+	return false
+}
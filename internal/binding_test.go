@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MaiBeng/protoc-gen-postman/google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// testFile returns a proto3 file of package pkg with the messages Request, Child and Response,
+// and a service Service of methods taking a Request and returning a Response
+func testFile(name, pkg string, methods ...*descriptorpb.MethodDescriptorProto) *descriptorpb.FileDescriptorProto {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String("." + pkg + "." + typeName)
+		}
+		return f
+	}
+	for _, method := range methods {
+		method.InputType = proto.String("." + pkg + ".Request")
+		method.OutputType = proto.String("." + pkg + ".Response")
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String(name),
+		Package: proto.String(pkg),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/" + strings.ReplaceAll(pkg, ".", "/"))},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Request"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("child", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "Child"),
+					field("page", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				},
+			},
+			{
+				Name: proto.String("Child"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("note", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				},
+			},
+			{
+				Name: proto.String("Response"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("parent", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "Response"),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name:   proto.String("Service"),
+			Method: methods,
+		}},
+	}
+}
+
+// testMethod returns a method annotated with rule, rule is left out when nil
+func testMethod(name string, rule *annotations.HttpRule) *descriptorpb.MethodDescriptorProto {
+	var method = &descriptorpb.MethodDescriptorProto{
+		Name:    proto.String(name),
+		Options: &descriptorpb.MethodOptions{},
+	}
+	if rule != nil {
+		proto.SetExtension(method.Options, annotations.E_Http, rule)
+	}
+
+	return method
+}
+
+// newTestPlugin returns a plugin generating all the files
+func newTestPlugin(t *testing.T, files ...*descriptorpb.FileDescriptorProto) *protogen.Plugin {
+	t.Helper()
+
+	var request = &pluginpb.CodeGeneratorRequest{ProtoFile: files}
+	for _, file := range files {
+		request.FileToGenerate = append(request.FileToGenerate, file.GetName())
+	}
+	plugin, err := protogen.Options{}.New(request)
+	if err != nil {
+		t.Fatalf("protogen.Options.New error: %v", err)
+	}
+
+	return plugin
+}
+
+func TestGetBinding(t *testing.T) {
+	tests := []struct {
+		rule      *annotations.HttpRule
+		method    string
+		variables []string
+		bodyAll   bool
+		body      string
+		bound     []string
+		unbound   []string
+	}{
+		{
+			rule:      &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name}"}},
+			method:    "GET",
+			variables: []string{"name"},
+			bound:     []string{"name"},
+			unbound:   []string{"child", "child.id", "page"},
+		},
+		{
+			rule:      &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/{child.id}/items"}},
+			method:    "GET",
+			variables: []string{"child.id"},
+			bound:     []string{"child.id"},
+			unbound:   []string{"name", "child.note", "page"},
+		},
+		{
+			rule:      &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name=shelves/*}:cancel"}, Body: "*"},
+			method:    "POST",
+			variables: []string{"name"},
+			bodyAll:   true,
+			bound:     []string{"name", "child", "page"},
+		},
+		{
+			rule:      &annotations.HttpRule{Pattern: &annotations.HttpRule_Patch{Patch: "/v1/{name}"}, Body: "child"},
+			method:    "PATCH",
+			variables: []string{"name"},
+			body:      "child",
+			bound:     []string{"name", "child"},
+			unbound:   []string{"page"},
+		},
+		{
+			rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Custom{
+				Custom: &annotations.CustomHttpPattern{Kind: "SEARCH", Path: "/v1/items"},
+			}},
+			method:  "SEARCH",
+			unbound: []string{"name", "child", "page"},
+		},
+	}
+
+	plugin := newTestPlugin(t, testFile("a.proto", "a", testMethod("Get", nil)))
+	method := plugin.Files[0].Services[0].Methods[0]
+	p := NewPostman()
+	for _, tt := range tests {
+		binding, err := p.getBinding(method, tt.rule)
+		if err != nil {
+			t.Errorf("getBinding(%v) error: %v", tt.rule, err)
+			continue
+		}
+
+		var variables []string
+		for _, variable := range binding.Variables {
+			variables = append(variables, variable.Key)
+		}
+		var body string
+		if binding.Body != nil {
+			body = string(binding.Body[0].Desc.Name())
+		}
+		if binding.Method != tt.method || !reflect.DeepEqual(variables, tt.variables) || binding.BodyAll != tt.bodyAll || body != tt.body {
+			t.Errorf("getBinding(%v) = %s %v body_all=%v body=%q, want %s %v body_all=%v body=%q",
+				tt.rule, binding.Method, variables, binding.BodyAll, body, tt.method, tt.variables, tt.bodyAll, tt.body)
+		}
+		for _, name := range tt.bound {
+			if !binding.IsBound(strings.Split(name, ".")) {
+				t.Errorf("getBinding(%v).IsBound(%q) = false, want true", tt.rule, name)
+			}
+		}
+		for _, name := range tt.unbound {
+			if binding.IsBound(strings.Split(name, ".")) {
+				t.Errorf("getBinding(%v).IsBound(%q) = true, want false", tt.rule, name)
+			}
+		}
+	}
+}
+
+func TestGetBindingError(t *testing.T) {
+	rules := []*annotations.HttpRule{
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{missing}"}},
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name"}},
+		{Pattern: &annotations.HttpRule_Post{Post: "/v1/items"}, Body: "missing"},
+	}
+
+	plugin := newTestPlugin(t, testFile("a.proto", "a", testMethod("Get", nil)))
+	method := plugin.Files[0].Services[0].Methods[0]
+	for _, rule := range rules {
+		if _, err := NewPostman().getBinding(method, rule); err == nil {
+			t.Errorf("getBinding(%v) want an error", rule)
+		}
+	}
+}
+
+func TestGetPaths(t *testing.T) {
+	plugin := newTestPlugin(t, testFile("a.proto", "a", testMethod("Get", nil)))
+	method := plugin.Files[0].Services[0].Methods[0]
+	binding, err := NewPostman().getBinding(method, &annotations.HttpRule{
+		Pattern: &annotations.HttpRule_Post{Post: "/v1/{child.id}/items/{name}:cancel"},
+	})
+	if err != nil {
+		t.Fatalf("getBinding error: %v", err)
+	}
+
+	var verbs []bool
+	paths := binding.GetPaths(func(v *BindingVariable, verb bool) string {
+		verbs = append(verbs, verb)
+		return "<" + v.Key + ">"
+	})
+	if want := []string{"v1", "<child.id>", "items", "<name>:cancel"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("GetPaths = %q, want %q", paths, want)
+	}
+	if want := []bool{false, true}; !reflect.DeepEqual(verbs, want) {
+		t.Errorf("GetPaths verbs = %v, want %v", verbs, want)
+	}
+}
@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// insomniaVariableChars matches the characters which are not allowed in insomnia environment variable names
+var insomniaVariableChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+const (
+	INSOMNIA_SUFFIX        = ".insomnia.json"
+	INSOMNIA_EXPORT_FORMAT = 4
+	INSOMNIA_SOURCE        = "protoc-gen-postman"
+)
+
+type InsomniaExport struct {
+	Type         string              `json:"_type"`
+	ExportFormat int                 `json:"__export_format"`
+	ExportSource string              `json:"__export_source"`
+	Resources    []*InsomniaResource `json:"resources"`
+}
+
+// InsomniaResource is a workspace, environment, request_group or request of the export
+type InsomniaResource struct {
+	ID          string            `json:"_id"`
+	Type        string            `json:"_type"`
+	ParentID    *string           `json:"parentId"` // null for the workspace
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	MetaSortKey int               `json:"metaSortKey"`
+	Scope       string            `json:"scope,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+	Method      string            `json:"method,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Body        *InsomniaBody     `json:"body,omitempty"`
+	Headers     []*InsomniaPair   `json:"headers,omitempty"`
+	Parameters  []*InsomniaPair   `json:"parameters,omitempty"`
+}
+
+type InsomniaBody struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type InsomniaPair struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// GenerateInsomnia writes an insomnia v4 export converted from the collection of the services in files
+func (p *Postman) GenerateInsomnia(plugin *protogen.Plugin, filename, name string, files []*protogen.File) error {
+	g := plugin.NewGeneratedFile(filename, files[len(files)-1].GoImportPath)
+
+	collection, err := p.GetCollection(name, files)
+	if err != nil {
+		return err
+	}
+
+	// id 由文件名和 item 的位置生成，重新导入时覆盖之前导入的资源
+	workspaceID := p.insomniaID("wrk", filename)
+	var out = InsomniaExport{
+		Type:         "export",
+		ExportFormat: INSOMNIA_EXPORT_FORMAT,
+		ExportSource: INSOMNIA_SOURCE,
+		Resources: []*InsomniaResource{{
+			ID:          workspaceID,
+			Type:        "workspace",
+			Name:        collection.Info.Name,
+			Description: p.getPackageDesc(files),
+			Scope:       "collection",
+		}},
+	}
+
+	// base_url 中的 postman 变量和没有示例值的 path variable 作为环境变量
+	var data = make(map[string]string)
+	for _, match := range serverVariable.FindAllStringSubmatch(p.BaseURL, -1) {
+		data[p.insomniaVariable(match[1])] = SERVER_VAR_DEFAULT
+	}
+	resources := p.getInsomniaResources(workspaceID, collection.Item, data)
+
+	out.Resources = append(out.Resources, &InsomniaResource{
+		ID:       p.insomniaID("env", workspaceID),
+		Type:     "environment",
+		ParentID: &workspaceID,
+		Name:     "Base Environment",
+		Data:     data,
+	})
+	out.Resources = append(out.Resources, resources...)
+
+	outStr, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	g.P(string(outStr))
+
+	return nil
+}
+
+// getInsomniaResources converts the items under parentID, folders become request groups,
+// the path variables referenced as environment variables are added to data
+func (p *Postman) getInsomniaResources(parentID string, items []*Item, data map[string]string) []*InsomniaResource {
+	var resources []*InsomniaResource
+	for i, item := range items {
+		parentID := parentID
+		if item.Request == nil {
+			id := p.insomniaID("fld", parentID+"/"+strconv.Itoa(i)+"/"+item.Name)
+			resources = append(resources, &InsomniaResource{
+				ID:          id,
+				Type:        "request_group",
+				ParentID:    &parentID,
+				Name:        item.Name,
+				Description: item.Description,
+				MetaSortKey: i,
+			})
+			resources = append(resources, p.getInsomniaResources(id, item.Item, data)...)
+			continue
+		}
+
+		request := item.Request
+		var resource = &InsomniaResource{
+			ID:          p.insomniaID("req", parentID+"/"+strconv.Itoa(i)+"/"+item.Name),
+			Type:        "request",
+			ParentID:    &parentID,
+			Name:        item.Name,
+			Description: request.Description,
+			MetaSortKey: i,
+			Method:      request.Method,
+			URL:         p.insomniaTemplate(p.getInsomniaURL(item, data)),
+		}
+		if request.Body != nil {
+			resource.Body = &InsomniaBody{
+				MimeType: JSON_CONTENT_TYPE,
+				Text:     request.Body.Raw,
+			}
+		}
+		for _, header := range request.Header {
+			resource.Headers = append(resource.Headers, &InsomniaPair{
+				Name:  header.Key,
+				Value: p.insomniaTemplate(header.Value),
+			})
+		}
+		for _, query := range request.URL.Query {
+			resource.Parameters = append(resource.Parameters, &InsomniaPair{
+				Name:        query.Key,
+				Value:       query.Value,
+				Description: query.Description,
+			})
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources
+}
+
+// getInsomniaURL returns the url of the request of item without query params, insomnia v4 has no path variables,
+// so they are replaced by the sample values of the request, or by an environment variable added to data without a sample
+func (p *Postman) getInsomniaURL(item *Item, data map[string]string) string {
+	url := item.Request.URL
+	var paths []string
+	for _, segment := range url.Path {
+		for _, variable := range url.Variable {
+			if segment == ":"+variable.Key {
+				segment = p.insomniaValue(item.namespace+"."+variable.Key, variable.Value, data)
+				break
+			}
+		}
+		// 带 :verb 的最后一段引用的 collection 变量
+		for _, variable := range item.variables {
+			if strings.HasPrefix(segment, "{{"+variable.Key+"}}") {
+				segment = p.insomniaValue(variable.Key, variable.Value, data) + strings.TrimPrefix(segment, "{{"+variable.Key+"}}")
+				break
+			}
+		}
+		paths = append(paths, segment)
+	}
+
+	return strings.Join(url.Host, "") + "/" + strings.Join(paths, "/")
+}
+
+// insomniaValue returns the sample value of the path variable key, or a {{key}} environment variable added to data without a sample
+func (p *Postman) insomniaValue(key, value string, data map[string]string) string {
+	if value != "" {
+		return value
+	}
+
+	data[p.insomniaVariable(key)] = ""
+	return "{{" + key + "}}"
+}
+
+// insomniaTemplate rewrites the postman variables {{name}} to the insomnia ones {{ _.name }}
+func (p *Postman) insomniaTemplate(s string) string {
+	return serverVariable.ReplaceAllStringFunc(s, func(match string) string {
+		return "{{ _." + p.insomniaVariable(serverVariable.FindStringSubmatch(match)[1]) + " }}"
+	})
+}
+
+// insomniaVariable returns the name of the environment variable of key, eg: user.id => user_id,
+// since insomnia reads _.user.id as the id of the user object
+func (p *Postman) insomniaVariable(key string) string {
+	return insomniaVariableChars.ReplaceAllString(key, "_")
+}
+
+// insomniaID returns a stable resource id of the seed
+func (p *Postman) insomniaID(prefix, seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return prefix + "_" + hex.EncodeToString(sum[:])[:16]
+}
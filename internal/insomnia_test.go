@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/MaiBeng/protoc-gen-postman/google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestGenerateInsomnia(t *testing.T) {
+	plugin := newTestPlugin(t, testFile("a.proto", "a",
+		testMethod("Get", &annotations.HttpRule{
+			Pattern: &annotations.HttpRule_Get{Get: "/v1/{name}"},
+			AdditionalBindings: []*annotations.HttpRule{
+				{Pattern: &annotations.HttpRule_Get{Get: "/v1/{child.id}/{name=shelves/*}:cancel"}},
+			},
+		}),
+		testMethod("Delete", &annotations.HttpRule{Pattern: &annotations.HttpRule_Delete{Delete: "/v1/{name=items/*}"}}),
+	))
+	p := NewPostman()
+	if err := p.GenerateInsomnia(plugin, "api.insomnia.json", "api", plugin.Files); err != nil {
+		t.Fatalf("GenerateInsomnia error: %v", err)
+	}
+	response := plugin.Response()
+	if response.Error != nil {
+		t.Fatalf("GenerateInsomnia response error: %s", response.GetError())
+	}
+
+	var out InsomniaExport
+	if err := json.Unmarshal([]byte(response.File[0].GetContent()), &out); err != nil {
+		t.Fatalf("GenerateInsomnia wrote an invalid export: %v", err)
+	}
+
+	// 每个请求使用自己的示例值，没有示例值的 path variable 引用以请求命名的环境变量
+	var urls []string
+	var data map[string]string
+	for _, resource := range out.Resources {
+		switch resource.Type {
+		case "request":
+			urls = append(urls, resource.Method+" "+resource.URL)
+		case "environment":
+			data = resource.Data
+		}
+	}
+	wantURLs := []string{
+		"GET {{ _.domain }}/v1/{{ _.a_Service_Get_name }}",
+		"GET {{ _.domain }}/v1/{{ _.a_Service_Get_1_child_id }}/shelves/*:cancel",
+		"DELETE {{ _.domain }}/v1/items/*",
+	}
+	if !reflect.DeepEqual(urls, wantURLs) {
+		t.Errorf("GenerateInsomnia urls = %q, want %q", urls, wantURLs)
+	}
+	wantData := map[string]string{
+		"domain":                   SERVER_VAR_DEFAULT,
+		"a_Service_Get_name":       "",
+		"a_Service_Get_1_child_id": "",
+	}
+	if !reflect.DeepEqual(data, wantData) {
+		t.Errorf("GenerateInsomnia environment = %v, want %v", data, wantData)
+	}
+}
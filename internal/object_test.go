@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestObjectOrder(t *testing.T) {
+	o := NewObject()
+	o.Set("b", 1)
+	o.Set("a", "x")
+	o.Set("c", nil)
+	o.Set("b", 2) // 覆盖已有的 key 不改变顺序
+
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(o.Keys(), want) {
+		t.Errorf("Keys() = %v, want %v", o.Keys(), want)
+	}
+	if v, ok := o.Get("b"); !ok || v != 2 {
+		t.Errorf("Get(%q) = %v, %v, want 2, true", "b", v, ok)
+	}
+	if _, ok := o.Get("d"); ok {
+		t.Errorf("Get(%q) found a missing key", "d")
+	}
+
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":2,"a":"x","c":null}`; string(b) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", b, want)
+	}
+}
+
+func TestObjectNested(t *testing.T) {
+	inner := NewObject()
+	inner.Set("z", []interface{}{1, "2"})
+	inner.Set("y", Truncated("(recursive a.B)"))
+
+	o := NewObject()
+	o.Set("k\"ey", inner)
+	o.Set("empty", NewObject())
+
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"k\"ey":{"z":[1,"2"],"y":"(recursive a.B)"},"empty":{}}`; string(b) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", b, want)
+	}
+}
+
+func TestObjectDelete(t *testing.T) {
+	o := NewObject()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+
+	o.Delete("b")
+	o.Delete("missing")
+	if want := []string{"a", "c"}; !reflect.DeepEqual(o.Keys(), want) {
+		t.Errorf("Keys() = %v, want %v", o.Keys(), want)
+	}
+	if _, ok := o.Get("b"); ok {
+		t.Errorf("Get(%q) found a deleted key", "b")
+	}
+
+	// 删除后重新设置的 key 排在最后
+	o.Set("b", 4)
+	if want := []string{"a", "c", "b"}; !reflect.DeepEqual(o.Keys(), want) {
+		t.Errorf("Keys() = %v, want %v", o.Keys(), want)
+	}
+
+	o.Delete("a")
+	o.Delete("c")
+	o.Delete("b")
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "{}" {
+		t.Errorf("MarshalJSON() = %s, want {}", b)
+	}
+}
@@ -0,0 +1,391 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/MaiBeng/protoc-gen-postman/google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	OPENAPI_VERSION        = "3.1.0"
+	OPENAPI_SUFFIX         = ".openapi.json"
+	COMPONENTS_PREFIX      = "#/components/schemas/"
+	SERVER_VAR_DEFAULT     = "http://localhost:8080"
+	JSON_CONTENT_TYPE      = "application/json"
+	OPERATION_ID_SEPARATOR = "_"
+)
+
+// serverVariable matches the postman variables in base_url, eg: {{domain}}
+var serverVariable = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+type OpenAPI struct {
+	OpenAPI    string             `json:"openapi"`
+	Info       *OpenAPIInfo       `json:"info"`
+	Servers    []*Server          `json:"servers,omitempty"`
+	Tags       []*Tag             `json:"tags,omitempty"`
+	Paths      *Object            `json:"paths"` // path => method => *Operation
+	Components *OpenAPIComponents `json:"components,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL       string                     `json:"url"`
+	Variables map[string]*ServerVariable `json:"variables,omitempty"`
+}
+
+type ServerVariable struct {
+	Default string `json:"default"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	Tags        []string     `json:"tags,omitempty"`
+	Summary     string       `json:"summary,omitempty"`
+	Description string       `json:"description,omitempty"`
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters,omitempty"`
+	RequestBody *RequestBody `json:"requestBody,omitempty"`
+	Responses   *Object      `json:"responses"` // status code => *OpenAPIResponse
+	Deprecated  bool         `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type OpenAPIComponents struct {
+	Schemas *Object `json:"schemas,omitempty"`
+}
+
+// GenerateOpenAPI writes an OpenAPI 3.1 document of the http bindings of the services in files
+func (p *Postman) GenerateOpenAPI(plugin *protogen.Plugin, filename, name, version string, files []*protogen.File) error {
+	g := plugin.NewGeneratedFile(filename, files[len(files)-1].GoImportPath)
+
+	var out = OpenAPI{
+		OpenAPI: OPENAPI_VERSION,
+		Info: &OpenAPIInfo{
+			Title:       name,
+			Description: p.getPackageDesc(files),
+			Version:     version,
+		},
+		Servers: []*Server{p.getServer()},
+		Paths:   NewObject(),
+	}
+
+	defs := NewSchemaDefs(COMPONENTS_PREFIX)
+	defs.NumberFormats = true
+	for _, file := range files {
+		for _, service := range file.Services {
+			out.Tags = append(out.Tags, p.getServiceTag(service))
+
+			for _, method := range service.Methods {
+				if err := p.addMethodOperations(out.Paths, method, defs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if len(defs.Defs.Keys()) > 0 {
+		out.Components = &OpenAPIComponents{Schemas: defs.Defs}
+	}
+
+	outStr, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	g.P(string(outStr))
+
+	return nil
+}
+
+// getServer returns the server of base_url, postman variables become server variables
+func (p *Postman) getServer() *Server {
+	var server = &Server{
+		URL: serverVariable.ReplaceAllString(p.BaseURL, "{$1}"),
+	}
+	for _, match := range serverVariable.FindAllStringSubmatch(p.BaseURL, -1) {
+		if server.Variables == nil {
+			server.Variables = make(map[string]*ServerVariable)
+		}
+		server.Variables[match[1]] = &ServerVariable{Default: SERVER_VAR_DEFAULT}
+	}
+
+	return server
+}
+
+// getServiceTag returns the tag grouping the operations of service
+func (p *Postman) getServiceTag(service *protogen.Service) *Tag {
+	return &Tag{
+		Name:        string(service.Desc.FullName()),
+		Description: p.getServiceDesc(service),
+	}
+}
+
+// addMethodOperations adds an operation to paths for every http binding of method,
+// a binding whose path and method are already in paths is left out
+func (p *Postman) addMethodOperations(paths *Object, method *protogen.Method, defs *SchemaDefs) error {
+	httpRules, err := p.getMethodRules(method)
+	if err != nil {
+		return err
+	}
+
+	desc, header := p.getMethodDescAndHeader(method.Comments.Leading, method.Comments.Trailing)
+	for i, httpRule := range httpRules {
+		binding, err := p.getBinding(method, httpRule)
+		if err != nil {
+			return err
+		}
+		switch binding.Method {
+		case "GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE":
+		default:
+			// OpenAPI 不支持其他的 custom 方法
+			continue
+		}
+
+		urlPath, pathParameters := p.getPathParameters(binding, defs)
+
+		// grpc-gateway 按注册顺序匹配，同一个 path 和方法只有先注册的 binding 生效
+		var pathItem = NewObject()
+		if value, ok := paths.Get(urlPath); ok {
+			pathItem = value.(*Object)
+		}
+		if _, ok := pathItem.Get(strings.ToLower(binding.Method)); ok {
+			continue
+		}
+
+		operation, err := p.getOperation(method, binding, desc, header, pathParameters, defs)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			operation.OperationID += OPERATION_ID_SEPARATOR + fmt.Sprint(i)
+		}
+
+		pathItem.Set(strings.ToLower(binding.Method), operation)
+		paths.Set(urlPath, pathItem)
+	}
+
+	return nil
+}
+
+// getPathParameters returns the OpenAPI path of binding and its path params,
+// the literals of the variable patterns are kept in the path, eg: /v1/{name=projects/*} => /v1/projects/{project}
+func (p *Postman) getPathParameters(binding *Binding, defs *SchemaDefs) (string, []*Parameter) {
+	var parameters []*Parameter
+	var names = make(map[string]bool)
+	var paramName = func(name string) string {
+		unique := name
+		for i := 1; names[unique]; i++ {
+			unique = name + fmt.Sprint(i)
+		}
+		names[unique] = true
+		return unique
+	}
+
+	paths := binding.GetPaths(func(variable *BindingVariable, verb bool) string {
+		field := variable.Fields[len(variable.Fields)-1]
+		description := p.fieldDescription(field)
+		if variable.Segment.Pattern == "" || variable.Segment.Pattern == "*" || variable.Segment.Pattern == "**" {
+			name := paramName(variable.Key)
+			parameters = append(parameters, &Parameter{
+				Name:        name,
+				In:          "path",
+				Description: description,
+				Required:    true,
+				Schema:      p.singularSchema(field, defs),
+			})
+			return "{" + name + "}"
+		}
+
+		// 与 protoc-gen-openapiv2 一致，pattern 中的 * 以前一段的单数命名，eg: projects/* => projects/{project}
+		segments := strings.Split(variable.Segment.Pattern, "/")
+		for i, segment := range segments {
+			if segment != "*" && segment != "**" {
+				continue
+			}
+
+			name := variable.Key + fmt.Sprint(i)
+			if i > 0 && segments[i-1] != "*" && segments[i-1] != "**" {
+				name = strings.TrimSuffix(segments[i-1], "s")
+			}
+			name = paramName(name)
+			segments[i] = "{" + name + "}"
+			parameters = append(parameters, &Parameter{
+				Name:        name,
+				In:          "path",
+				Description: strings.TrimPrefix(description+"\n\nSegment of `"+variable.Key+"`, pattern: `"+variable.Segment.Pattern+"`", "\n\n"),
+				Required:    true,
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+		return strings.Join(segments, "/")
+	})
+
+	return "/" + strings.Join(paths, "/"), parameters
+}
+
+// getOperation returns the operation of a single http binding of method
+func (p *Postman) getOperation(method *protogen.Method, binding *Binding, desc string, header []*Header, pathParameters []*Parameter, defs *SchemaDefs) (*Operation, error) {
+	// operationId 以 service 的全名开头，不同 package 的同名 service 不会重复
+	var operation = &Operation{
+		Tags:        []string{string(method.Parent.Desc.FullName())},
+		Summary:     strings.SplitN(desc, "\n", 2)[0],
+		OperationID: string(method.Parent.Desc.FullName()) + OPERATION_ID_SEPARATOR + string(method.Desc.Name()),
+		Parameters:  pathParameters,
+		Responses:   NewObject(),
+	}
+	var descs []string
+	if desc != "" {
+		descs = append(descs, desc)
+	}
+	descs = append(descs, p.getMethodInfo(method, binding.Rule))
+	operation.Description = strings.Join(descs, "\n\n")
+	if options, ok := method.Desc.Options().(*descriptorpb.MethodOptions); ok {
+		operation.Deprecated = options.GetDeprecated()
+	}
+
+	switch {
+	case binding.BodyAll:
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				JSON_CONTENT_TYPE: {Schema: p.messageSchema(method.Input, defs)},
+			},
+		}
+	case binding.Body != nil:
+		field := binding.Body[0]
+		operation.RequestBody = &RequestBody{
+			Description: p.getComments(field.Comments.Leading, field.Comments.Trailing),
+			Required:    true,
+			Content: map[string]*MediaType{
+				JSON_CONTENT_TYPE: {Schema: p.fieldSchema(field, defs)},
+			},
+		}
+	}
+	operation.Parameters = append(operation.Parameters, p.getQueryParameters(method.Input, binding, nil, "", nil, defs)...)
+
+	// grpc metadata 的请求头
+	for _, h := range header {
+		operation.Parameters = append(operation.Parameters, &Parameter{
+			Name:   h.Key,
+			In:     "header",
+			Schema: &Schema{Type: "string"},
+		})
+	}
+
+	response, err := p.getOperationResponse(method, binding.Rule, defs)
+	if err != nil {
+		return nil, err
+	}
+	operation.Responses.Set("200", response)
+
+	return operation, nil
+}
+
+// getQueryParameters returns the query params of the fields of message which are not bound to the path or the body,
+// fieldPath and prefix are the proto and json paths of message, path is the messages being expanded
+func (p *Postman) getQueryParameters(message *protogen.Message, binding *Binding, fieldPath []string, prefix string, path []protoreflect.FullName, defs *SchemaDefs) []*Parameter {
+	// 与 transParmas 一致，递归的 message 不再展开
+	for _, n := range path {
+		if n == message.Desc.FullName() {
+			return nil
+		}
+	}
+	path = append(path[:len(path):len(path)], message.Desc.FullName())
+
+	var parameters []*Parameter
+	for _, field := range message.Fields {
+		name := append(fieldPath[:len(fieldPath):len(fieldPath)], string(field.Desc.Name()))
+		if binding.IsBound(name) {
+			continue
+		}
+
+		// query 中不支持 map 和 repeated message
+		if field.Desc.IsMap() {
+			continue
+		}
+		if field.Message != nil {
+			if _, ok := p.wellKnownSchema(field.Message, defs); !ok {
+				if !field.Desc.IsList() {
+					parameters = append(parameters, p.getQueryParameters(field.Message, binding, name, prefix+p.fieldName(field)+".", path, defs)...)
+				}
+				continue
+			}
+		}
+
+		// query 中的 message 不会是 null
+		schema := p.singularSchema(field, defs)
+		if field.Desc.IsList() {
+			schema = p.fieldSchema(field, defs)
+		}
+		parameters = append(parameters, &Parameter{
+			Name:        prefix + p.fieldName(field),
+			In:          "query",
+			Description: p.fieldDescription(field),
+			Required:    field.Desc.Cardinality() == protoreflect.Required,
+			Schema:      schema,
+		})
+	}
+
+	return parameters
+}
+
+// getOperationResponse returns the 200 response of the output message, selected by response_body
+func (p *Postman) getOperationResponse(method *protogen.Method, httpRule *annotations.HttpRule, defs *SchemaDefs) (*OpenAPIResponse, error) {
+	schema := p.messageSchema(method.Output, defs)
+	if responseBody := httpRule.GetResponseBody(); responseBody != "" {
+		fields, err := p.getFields(method.Output, []string{responseBody})
+		if err != nil {
+			return nil, fmt.Errorf("response_body %q: %v", responseBody, err)
+		}
+		schema = p.fieldSchema(fields[0], defs)
+	}
+
+	// grpc-gateway 的 server streaming 响应的每条消息为 {"result": ...}
+	if method.Desc.IsStreamingServer() {
+		var properties = NewObject()
+		properties.Set("result", schema)
+		schema = &Schema{Type: "object", Properties: properties}
+	}
+
+	return &OpenAPIResponse{
+		Description: "OK",
+		Content: map[string]*MediaType{
+			JSON_CONTENT_TYPE: {Schema: schema},
+		},
+	}, nil
+}
@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/MaiBeng/protoc-gen-postman/google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func TestGetQueryParameters(t *testing.T) {
+	tests := []struct {
+		rule *annotations.HttpRule
+		want []string
+	}{
+		{
+			rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/items"}},
+			want: []string{"name", "child.id", "child.note", "page"},
+		},
+		{
+			rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/{child.id}"}},
+			want: []string{"name", "child.note", "page"},
+		},
+		{
+			rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name}"}, Body: "*"},
+		},
+		{
+			rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name}"}, Body: "child"},
+			want: []string{"page"},
+		},
+	}
+
+	plugin := newTestPlugin(t, testFile("a.proto", "a", testMethod("Get", nil)))
+	method := plugin.Files[0].Services[0].Methods[0]
+	p := NewPostman()
+	for _, tt := range tests {
+		binding, err := p.getBinding(method, tt.rule)
+		if err != nil {
+			t.Errorf("getBinding(%v) error: %v", tt.rule, err)
+			continue
+		}
+
+		var names []string
+		for _, parameter := range p.getQueryParameters(method.Input, binding, nil, "", nil, NewSchemaDefs(COMPONENTS_PREFIX)) {
+			if parameter.In != "query" {
+				t.Errorf("getQueryParameters(%v) %q in %q, want query", tt.rule, parameter.Name, parameter.In)
+			}
+			names = append(names, parameter.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("getQueryParameters(%v) = %q, want %q", tt.rule, names, tt.want)
+		}
+	}
+}
+
+// generateOpenAPI returns the paths of the OpenAPI document of files, path => method => operation
+func generateOpenAPI(t *testing.T, plugin *protogen.Plugin) map[string]map[string]*Operation {
+	t.Helper()
+
+	if err := NewPostman().GenerateOpenAPI(plugin, "api.openapi.json", "api", "1", plugin.Files); err != nil {
+		t.Fatalf("GenerateOpenAPI error: %v", err)
+	}
+	response := plugin.Response()
+	if response.Error != nil {
+		t.Fatalf("GenerateOpenAPI response error: %s", response.GetError())
+	}
+
+	var out struct {
+		Paths map[string]map[string]*Operation `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(response.File[0].GetContent()), &out); err != nil {
+		t.Fatalf("GenerateOpenAPI wrote an invalid document: %v", err)
+	}
+
+	return out.Paths
+}
+
+func TestGenerateOpenAPI(t *testing.T) {
+	plugin := newTestPlugin(t, testFile("a.proto", "a",
+		testMethod("Get", &annotations.HttpRule{
+			Pattern: &annotations.HttpRule_Get{Get: "/v1/{name=projects/*/locations/*}"},
+			AdditionalBindings: []*annotations.HttpRule{
+				{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name=organizations/*/locations/*}"}},
+				{Pattern: &annotations.HttpRule_Get{Get: "/v1/{child.id}/children"}},
+			},
+		}),
+		testMethod("Cancel", &annotations.HttpRule{
+			Pattern: &annotations.HttpRule_Post{Post: "/v1/{name=projects/*/operations/*}:cancel"},
+			Body:    "*",
+		}),
+		testMethod("Unannotated", nil),
+	))
+	paths := generateOpenAPI(t, plugin)
+
+	tests := []struct {
+		path, method string
+		operationID  string
+		parameters   []string // name:in
+		body         bool
+	}{
+		{
+			path:        "/v1/projects/{project}/locations/{location}",
+			method:      "get",
+			operationID: "a.Service_Get",
+			parameters:  []string{"project:path", "location:path", "child.id:query", "child.note:query", "page:query"},
+		},
+		{
+			path:        "/v1/organizations/{organization}/locations/{location}",
+			method:      "get",
+			operationID: "a.Service_Get_1",
+			parameters:  []string{"organization:path", "location:path", "child.id:query", "child.note:query", "page:query"},
+		},
+		{
+			path:        "/v1/{child.id}/children",
+			method:      "get",
+			operationID: "a.Service_Get_2",
+			parameters:  []string{"child.id:path", "name:query", "child.note:query", "page:query"},
+		},
+		{
+			path:        "/v1/projects/{project}/operations/{operation}:cancel",
+			method:      "post",
+			operationID: "a.Service_Cancel",
+			parameters:  []string{"project:path", "operation:path"},
+			body:        true,
+		},
+	}
+
+	if len(paths) != len(tests) {
+		t.Errorf("GenerateOpenAPI wrote %d paths, want %d", len(paths), len(tests))
+	}
+	for _, tt := range tests {
+		operation := paths[tt.path][tt.method]
+		if operation == nil {
+			t.Errorf("GenerateOpenAPI has no %s %s", tt.method, tt.path)
+			continue
+		}
+
+		var parameters []string
+		for _, parameter := range operation.Parameters {
+			parameters = append(parameters, parameter.Name+":"+parameter.In)
+		}
+		if operation.OperationID != tt.operationID || !reflect.DeepEqual(parameters, tt.parameters) || (operation.RequestBody != nil) != tt.body {
+			t.Errorf("%s %s = %s %v body=%v, want %s %v body=%v", tt.method, tt.path,
+				operation.OperationID, parameters, operation.RequestBody != nil, tt.operationID, tt.parameters, tt.body)
+		}
+	}
+}
+
+func TestGenerateOpenAPIPackages(t *testing.T) {
+	// 两个 package 的同名 service 绑定了 pattern 不同的 path，以及完全相同的 path
+	plugin := newTestPlugin(t,
+		testFile("a.proto", "a",
+			testMethod("Cancel", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name=a/*}:cancel"}}),
+			testMethod("Wait", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name}:wait"}}),
+		),
+		testFile("b.proto", "b",
+			testMethod("Cancel", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name=b/*}:cancel"}}),
+			testMethod("Wait", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name}:wait"}}),
+		),
+	)
+	paths := generateOpenAPI(t, plugin)
+
+	var operations []string
+	for path, pathItem := range paths {
+		for method, operation := range pathItem {
+			operations = append(operations, method+" "+path+" "+operation.OperationID)
+		}
+	}
+	sort.Strings(operations)
+	want := []string{
+		"post /v1/a/{a}:cancel a.Service_Cancel",
+		"post /v1/b/{b}:cancel b.Service_Cancel",
+		"post /v1/{name}:wait a.Service_Wait",
+	}
+	if !reflect.DeepEqual(operations, want) {
+		t.Errorf("GenerateOpenAPI operations = %q, want %q", operations, want)
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// 没有 google.api.http 注解的方法的处理方式
+	UNANNOTATED_SKIP    = "skip"    // 跳过
+	UNANNOTATED_DEFAULT = "default" // 按 grpc-gateway 的默认路由 POST /package.Service/Method 生成
+
+	// 目录的分组方式
+	GROUP_PACKAGE = "package" // package/service/method
+	GROUP_SERVICE = "service" // service/method
+	GROUP_NONE    = "none"    // method
+
+	// 输出文件的拆分方式
+	OUTPUT_SINGLE  = "single"  // 所有内容生成到 filename 中
+	OUTPUT_PACKAGE = "package" // 每个 proto package 生成一个 collection
+	OUTPUT_FILE    = "file"    // 每个 proto 文件生成一个 collection
+
+	// collection 版本号的生成方式
+	VERSION_HASH = "hash" // proto 文件内容的 hash
+	VERSION_TIME = "time" // 生成时间
+
+	// 请求的名称
+	ITEM_NAME_METHOD  = "method"  // 方法名
+	ITEM_NAME_SUMMARY = "summary" // 方法注释的第一行，没有注释时使用方法名
+
+	// 输出文件的格式
+	FORMAT_POSTMAN  = "postman"  // postman collection v2.1
+	FORMAT_OPENAPI  = "openapi"  // OpenAPI 3.1
+	FORMAT_INSOMNIA = "insomnia" // insomnia v4 export
+
+	// body、query 和 path variable 中字段的命名方式
+	FIELD_NAMES_JSON  = "json"  // json_name，默认为 lowerCamelCase，与 grpc-gateway 默认的 protojson 一致
+	FIELD_NAMES_PROTO = "proto" // proto 中定义的字段名
+)
+
+type Postman struct {
+	Filename     string // 生成文件的文件名
+	Format       string
+	Output       string
+	Name         string // collection 名称，为空时按版本号生成
+	Version      string
+	BaseURL      string // 请求的 host
+	MaxDepth     uint32 // message 嵌套的最大展开层数
+	FieldNames   string
+	Int64Numbers bool // 64 位整数生成为数字而不是 protojson 的字符串
+	Tests        bool // 生成校验响应的测试脚本
+	JSONSchema   bool // 为方法的请求和响应 message 生成 json schema 文件
+	Group        string
+	ItemName     string
+	Unannotated  string
+}
+
+func NewPostman() *Postman {
+	return &Postman{
+		Filename:    FILENAME,
+		Format:      FORMAT_POSTMAN,
+		Output:      OUTPUT_SINGLE,
+		Version:     VERSION_HASH,
+		BaseURL:     "{{domain}}",
+		MaxDepth:    32,
+		FieldNames:  FIELD_NAMES_JSON,
+		Group:       GROUP_PACKAGE,
+		ItemName:    ITEM_NAME_METHOD,
+		Tests:       true,
+		Unannotated: UNANNOTATED_SKIP,
+	}
+}
+
+// Set parses a single plugin parameter passed by --postman_opt, it matches protogen.Options.ParamFunc
+func (p *Postman) Set(name, value string) error {
+	switch name {
+	case "filename":
+		if value == "" {
+			return fmt.Errorf("parameter %q must not be empty", name)
+		}
+		p.Filename = value
+	case "format":
+		return p.setEnum(&p.Format, name, value, FORMAT_POSTMAN, FORMAT_OPENAPI, FORMAT_INSOMNIA)
+	case "output":
+		return p.setEnum(&p.Output, name, value, OUTPUT_SINGLE, OUTPUT_PACKAGE, OUTPUT_FILE)
+	case "name":
+		p.Name = value
+	case "version":
+		return p.setEnum(&p.Version, name, value, VERSION_HASH, VERSION_TIME)
+	case "base_url":
+		if value == "" {
+			return fmt.Errorf("parameter %q must not be empty", name)
+		}
+		p.BaseURL = value
+	case "max_depth":
+		depth, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("bad value for parameter %q: want a non-negative integer", name)
+		}
+		p.MaxDepth = uint32(depth)
+	case "field_names":
+		return p.setEnum(&p.FieldNames, name, value, FIELD_NAMES_JSON, FIELD_NAMES_PROTO)
+	case "int64_numbers":
+		return p.setBool(&p.Int64Numbers, name, value)
+	case "tests":
+		return p.setBool(&p.Tests, name, value)
+	case "json_schema":
+		return p.setBool(&p.JSONSchema, name, value)
+	case "group":
+		return p.setEnum(&p.Group, name, value, GROUP_PACKAGE, GROUP_SERVICE, GROUP_NONE)
+	case "item_name":
+		return p.setEnum(&p.ItemName, name, value, ITEM_NAME_METHOD, ITEM_NAME_SUMMARY)
+	case "unannotated":
+		return p.setEnum(&p.Unannotated, name, value, UNANNOTATED_SKIP, UNANNOTATED_DEFAULT)
+	default:
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+
+	return nil
+}
+
+func (p *Postman) setEnum(dst *string, name, value string, allowed ...string) error {
+	for _, v := range allowed {
+		if v == value {
+			*dst = value
+			return nil
+		}
+	}
+
+	return fmt.Errorf("bad value for parameter %q: want one of %q", name, allowed)
+}
+
+func (p *Postman) setBool(dst *bool, name, value string) error {
+	switch value {
+	case "true", "":
+		*dst = true
+	case "false":
+		*dst = false
+	default:
+		return fmt.Errorf(`bad value for parameter %q: want "true" or "false"`, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	p := NewPostman()
+	params := []struct {
+		name, value string
+	}{
+		{"filename", "api.postman_collection.json"},
+		{"output", OUTPUT_PACKAGE},
+		{"name", ""},
+		{"max_depth", "0"},
+		{"int64_numbers", ""},
+		{"tests", "false"},
+		{"group", GROUP_NONE},
+	}
+	for _, param := range params {
+		if err := p.Set(param.name, param.value); err != nil {
+			t.Errorf("Set(%q, %q) error: %v", param.name, param.value, err)
+		}
+	}
+
+	if p.Filename != "api.postman_collection.json" || p.Output != OUTPUT_PACKAGE || p.MaxDepth != 0 ||
+		!p.Int64Numbers || p.Tests || p.Group != GROUP_NONE {
+		t.Errorf("Set did not apply the parameters: %+v", p)
+	}
+}
+
+func TestSetError(t *testing.T) {
+	tests := []struct {
+		name, value string
+		err         string
+	}{
+		{"unknown", "x", `unknown parameter "unknown"`},
+		{"filename", "", `parameter "filename" must not be empty`},
+		{"base_url", "", `parameter "base_url" must not be empty`},
+		{"output", "dir", `bad value for parameter "output": want one of ["single" "package" "file"]`},
+		{"group", "", `bad value for parameter "group"`},
+		{"unannotated", "grpc", `bad value for parameter "unannotated"`},
+		{"max_depth", "-1", `bad value for parameter "max_depth": want a non-negative integer`},
+		{"max_depth", "4294967296", `bad value for parameter "max_depth"`},
+		{"max_depth", "", `bad value for parameter "max_depth"`},
+		{"tests", "yes", `bad value for parameter "tests": want "true" or "false"`},
+		{"int64_numbers", "1", `bad value for parameter "int64_numbers"`},
+	}
+
+	for _, tt := range tests {
+		p := NewPostman()
+		before := *p
+		err := p.Set(tt.name, tt.value)
+		if err == nil {
+			t.Errorf("Set(%q, %q) want an error", tt.name, tt.value)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("Set(%q, %q) error = %q, want %q", tt.name, tt.value, err, tt.err)
+		}
+		if *p != before {
+			t.Errorf("Set(%q, %q) changed the parameters on error", tt.name, tt.value)
+		}
+	}
+}
@@ -1,30 +1,36 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/MaiBeng/protoc-gen-postman/google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/reflect/protoreflect"
-	"reflect"
+	"math"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
 )
 
 // protoc --go_out=. ./proto/annotations.proto ./proto/http.proto
 
 const (
 	FILENAME           = "./source.postman_collection.json"
+	COLLECTION_SUFFIX  = ".postman_collection.json"
 	SCHEMA             = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
 	COMMENTS_HEADER    = "@reqMetadata"
 	GRPC_HEADER_PREFIX = "Grpc-Metadata-"
+	BYTES_SAMPLE       = "Ynl0ZXM=" // base64("bytes")
 )
 
-type Postman struct{}
-
 type Info struct {
 	Name   string `json:"name"`
 	Schema string `json:"schema"`
@@ -49,70 +55,187 @@ type Body struct {
 }
 
 type Query struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+type Variable struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
 }
 type URL struct {
-	Raw   string   `json:"raw"`
-	Host  []string `json:"host"`
-	Path  []string `json:"path"`
-	Query []*Query `json:"query"`
+	Raw      string      `json:"raw"`
+	Host     []string    `json:"host"`
+	Path     []string    `json:"path"`
+	Query    []*Query    `json:"query"`
+	Variable []*Variable `json:"variable,omitempty"`
 }
 
 type Request struct {
-	Method string    `json:"method"`
-	Header []*Header `json:"header"`
-	Body   *Body     `json:"body"`
-	URL    *URL      `json:"url"`
+	Method      string    `json:"method"`
+	Header      []*Header `json:"header"`
+	Body        *Body     `json:"body"`
+	URL         *URL      `json:"url"`
+	Description string    `json:"description,omitempty"`
+}
+
+type Response struct {
+	Name            string    `json:"name"`
+	OriginalRequest *Request  `json:"originalRequest"`
+	Status          string    `json:"status"`
+	Code            int       `json:"code"`
+	PreviewLanguage string    `json:"_postman_previewlanguage"` // 固定值 json
+	Header          []*Header `json:"header"`
+	Body            string    `json:"body"`
+}
+
+type Script struct {
+	Type string   `json:"type"` // 固定值 text/javascript
+	Exec []string `json:"exec"`
+}
+type Event struct {
+	Listen string  `json:"listen"`
+	Script *Script `json:"script"`
 }
 
 type Item struct {
-	Name    string   `json:"name"`
-	Request *Request `json:"request"` // empty when is folder
-	Item    []*Item  `json:"item"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Event       []*Event    `json:"event,omitempty"`
+	Request     *Request    `json:"request"` // empty when is folder
+	Response    []*Response `json:"response,omitempty"`
+	Item        []*Item     `json:"item"`
+
+	namespace string      // prefix of the variables of the request, eg: test.Service.Method
+	variables []*Variable // collection variables referenced by the request
 }
 
+// Truncated is the placeholder of nested messages which are not expanded
+type Truncated string
+
 type PostmanGenerated struct {
-	Info *Info   `json:"info"`
-	Item []*Item `json:"item,omitempty"`
+	Info     *Info       `json:"info"`
+	Item     []*Item     `json:"item,omitempty"`
+	Variable []*Variable `json:"variable,omitempty"`
 }
 
-func (p Postman) Generate(plugin *protogen.Plugin) error {
+func (p *Postman) Generate(plugin *protogen.Plugin) error {
+	// 声明支持 proto3 optional，否则 protoc 拒绝为使用 optional 的文件调用插件
+	plugin.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
 	if len(plugin.Files) < 1 {
 		return nil
 	}
 
-	// 指定生成文件的文件名
-	version := time.Now().Format("20060102030405")
+	// 通过plugin.Fiels，我们可以拿到所有的输入的proto文件
+	// 按 output 参数把需要生成的文件分配到各个输出文件中
+	var outputs []string
+	var outputFiles = make(map[string][]*protogen.File)
+	for _, file := range plugin.Files {
+		if !file.Generate {
+			continue
+		}
 
-	// 创建一个文件生成器对象
-	g := plugin.NewGeneratedFile(FILENAME, plugin.Files[len(plugin.Files)-1].GoImportPath)
+		var output string
+		switch p.Output {
+		case OUTPUT_PACKAGE:
+			output = string(file.Desc.Package())
+		case OUTPUT_FILE:
+			output = strings.TrimSuffix(file.Desc.Path(), ".proto")
+		}
 
-	var out = PostmanGenerated{
-		Info: &Info{
-			Name:   "version." + version,
-			Schema: SCHEMA,
-		},
-		Item: nil,
+		if _, ok := outputFiles[output]; !ok {
+			outputs = append(outputs, output)
+		}
+		outputFiles[output] = append(outputFiles[output], file)
 	}
 
-	// 通过plugin.Fiels，我们可以拿到所有的输入的proto文件
-	// 如果我们需要对这个文件生成代码的话，那么就进入到generateFile()逻辑
+	for _, output := range outputs {
+		version, err := p.getVersion(outputFiles[output])
+		if err != nil {
+			return err
+		}
 
-	// 合并同 packageName 的service
-	var packageFile = make(map[string][]*protogen.File)
-	for _, file := range plugin.Files {
-		if file.Generate {
-			packageFile[string(file.GoPackageName)] = append(packageFile[string(file.GoPackageName)], file)
+		filename, name := p.getFilename(output), "version."+version
+		if output != "" {
+			name = output + "." + version
+		}
+		if p.Name != "" {
+			name = p.Name
+		}
+
+		switch p.Format {
+		case FORMAT_OPENAPI:
+			err = p.GenerateOpenAPI(plugin, filename, name, version, outputFiles[output])
+		case FORMAT_INSOMNIA:
+			err = p.GenerateInsomnia(plugin, filename, name, outputFiles[output])
+		default:
+			err = p.GenerateCollection(plugin, filename, name, outputFiles[output])
 		}
-	}
-	for name, files := range packageFile {
-		item, err := p.GetFilesItem(name, files)
 		if err != nil {
 			return err
 		}
+	}
 
-		out.Item = append(out.Item, item)
+	if p.JSONSchema {
+		var files []*protogen.File
+		for _, output := range outputs {
+			files = append(files, outputFiles[output]...)
+		}
+		return p.GenerateSchemas(plugin, files)
+	}
+
+	return nil
+}
+
+// getFilename returns the name of the file generated for output, split outputs are generated in the folder of filename
+func (p *Postman) getFilename(output string) string {
+	var suffix = COLLECTION_SUFFIX
+	switch p.Format {
+	case FORMAT_OPENAPI:
+		suffix = OPENAPI_SUFFIX
+	case FORMAT_INSOMNIA:
+		suffix = INSOMNIA_SUFFIX
+	}
+
+	if output != "" {
+		return path.Join(path.Dir(p.Filename), output+suffix)
+	}
+	// 未指定 filename 时按输出格式使用对应的后缀
+	if p.Filename == FILENAME {
+		return strings.TrimSuffix(FILENAME, COLLECTION_SUFFIX) + suffix
+	}
+
+	return p.Filename
+}
+
+// getVersion returns the version stamp of the collection generated from files
+func (p *Postman) getVersion(files []*protogen.File) (string, error) {
+	if p.Version == VERSION_TIME {
+		return time.Now().Format("20060102150405"), nil
+	}
+
+	// 根据 proto 文件的内容生成，内容不变时版本号不变
+	hash := sha256.New()
+	for _, file := range files {
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(file.Proto)
+		if err != nil {
+			return "", err
+		}
+		hash.Write(b)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:12], nil
+}
+
+func (p *Postman) GenerateCollection(plugin *protogen.Plugin, filename, name string, files []*protogen.File) error {
+	// 创建一个文件生成器对象
+	g := plugin.NewGeneratedFile(filename, files[len(files)-1].GoImportPath)
+
+	out, err := p.GetCollection(name, files)
+	if err != nil {
+		return err
 	}
 
 	// 调用g.P就是往文件开始写入自己期待的代码
@@ -125,9 +248,77 @@ func (p Postman) Generate(plugin *protogen.Plugin) error {
 	return nil
 }
 
-func (p Postman) GetFilesItem(name string, files []*protogen.File) (*Item, error) {
+// GetCollection returns the collection of the services in files
+func (p *Postman) GetCollection(name string, files []*protogen.File) (*PostmanGenerated, error) {
+	var out = &PostmanGenerated{
+		Info: &Info{
+			Name:   name,
+			Schema: SCHEMA,
+		},
+		Item: nil,
+	}
+
+	// 合并同 packageName 的service，按 package 第一次出现的顺序排列
+	var packageNames []string
+	var packageFile = make(map[string][]*protogen.File)
+	for _, file := range files {
+		packageName := string(file.GoPackageName)
+		if _, ok := packageFile[packageName]; !ok {
+			packageNames = append(packageNames, packageName)
+		}
+		packageFile[packageName] = append(packageFile[packageName], file)
+	}
+	for _, name := range packageNames {
+		item, err := p.GetFilesItem(name, packageFile[name])
+		if err != nil {
+			return nil, err
+		}
+
+		out.Item = append(out.Item, item)
+	}
+	out.Item = p.groupItems(out.Item)
+	out.Variable = p.getCollectionVariables(out.Item, nil)
+
+	return out, nil
+}
+
+// getCollectionVariables returns the collection variables referenced by the requests of items,
+// they are namespaced by the request so every request keeps its own sample
+func (p *Postman) getCollectionVariables(items []*Item, variables []*Variable) []*Variable {
+	for _, item := range items {
+		variables = append(variables, item.variables...)
+		variables = p.getCollectionVariables(item.Item, variables)
+	}
+
+	return variables
+}
+
+// groupItems regroups the package folders according to the group parameter
+func (p *Postman) groupItems(packageItems []*Item) []*Item {
+	var items = packageItems
+	switch p.Group {
+	case GROUP_SERVICE, GROUP_NONE:
+		items = nil
+		for _, packageItem := range packageItems {
+			items = append(items, packageItem.Item...)
+		}
+	}
+
+	if p.Group == GROUP_NONE {
+		var methodItems []*Item
+		for _, serviceItem := range items {
+			methodItems = append(methodItems, serviceItem.Item...)
+		}
+		items = methodItems
+	}
+
+	return items
+}
+
+func (p *Postman) GetFilesItem(name string, files []*protogen.File) (*Item, error) {
 	var fileItem = &Item{
-		Name: name,
+		Name:        name,
+		Description: p.getPackageDesc(files),
 	}
 
 	// Traverse Services
@@ -146,9 +337,34 @@ func (p Postman) GetFilesItem(name string, files []*protogen.File) (*Item, error
 
 }
 
-func (p Postman) GetServiceItem(service *protogen.Service) (*Item, error) {
+// getPackageDesc returns the comments of the package statements and the deprecated files
+func (p *Postman) getPackageDesc(files []*protogen.File) string {
+	var descs []string
+	for _, file := range files {
+		if options, ok := file.Desc.Options().(*descriptorpb.FileOptions); ok && options.GetDeprecated() {
+			descs = append(descs, "**Deprecated**: `"+file.Desc.Path()+"`")
+		}
+
+		// path [2] 为 FileDescriptorProto 的 package 字段
+		for _, location := range file.Proto.GetSourceCodeInfo().GetLocation() {
+			if len(location.GetPath()) != 1 || location.GetPath()[0] != 2 {
+				continue
+			}
+
+			comments := p.getComments(protogen.Comments(location.GetLeadingComments()), protogen.Comments(location.GetTrailingComments()))
+			if comments != "" {
+				descs = append(descs, comments)
+			}
+		}
+	}
+
+	return strings.Join(descs, "\n\n")
+}
+
+func (p *Postman) GetServiceItem(service *protogen.Service) (*Item, error) {
 	var serviceItem = &Item{
-		Name: service.GoName,
+		Name:        service.GoName,
+		Description: p.getServiceDesc(service),
 	}
 
 	// Traverse Methods
@@ -157,6 +373,9 @@ func (p Postman) GetServiceItem(service *protogen.Service) (*Item, error) {
 		if err != nil {
 			return nil, err
 		}
+		if methodItem == nil {
+			continue
+		}
 
 		serviceItem.Item = append(serviceItem.Item, methodItem)
 	}
@@ -164,148 +383,777 @@ func (p Postman) GetServiceItem(service *protogen.Service) (*Item, error) {
 	return serviceItem, nil
 }
 
-func (p Postman) GetMethodItem(method *protogen.Method) (*Item, error) {
-	// 因为我们通过method.Desc.Options() 拿到的数据类型是`interface{}` 类型
-	// 所以这里我们需要对Options，明确指定转换为 *descriptorpb.MethodOptions 类型
-	// 这样子就能拿到我们的MethodOption对象
-	options, ok := method.Desc.Options().(*descriptorpb.MethodOptions)
-	if !ok {
-		return nil, fmt.Errorf("method.Desc.Options err")
-	}
-
-	// PS：重点
-	// 这里我们看到我们借助了一个非protogen下的包的内容
-	// 原因就是，protobuf编译器会把自定义的Option全部指定为Extension，由于并非内置的属性和值
-	// protobuf官方是没办法拿到和你对应的可读的内容的，只能通过拿到经过序列化之后的数据。
-	// 因此，我们这里通过 proto.GetExtension的方法，把刚才annotations.proto单独编译好的 annotations.pb.proto 文件下的 annotations.E_HTTP 加载进来，
-	// 指定了我需要在自定义扩展的MethodOptions中，拿到该Http下里面的value
-	// 也因此，我们可以再经过一次类型转换，就可以拿到了具体的httpRule
-	httpRule, ok := proto.GetExtension(options, annotations.E_Http).(*annotations.HttpRule)
-	if !ok {
-		return nil, fmt.Errorf("proto.GetExtension err")
-	}
-
-	var requestMethod, urlHost string
-	if url := httpRule.GetPost(); url != "" {
-		requestMethod = "POST"
-		urlHost = url
-	} else if url = httpRule.GetGet(); url != "" {
-		requestMethod = "GET"
-		urlHost = url
-	} else {
-		requestMethod = "POST"
-		urlHost = url
-		//return nil, fmt.Errorf("unknown method.httpRule")
+// getServiceDesc returns the deprecated flag and the comments of service
+func (p *Postman) getServiceDesc(service *protogen.Service) string {
+	var descs []string
+	if options, ok := service.Desc.Options().(*descriptorpb.ServiceOptions); ok && options.GetDeprecated() {
+		descs = append(descs, "**Deprecated**")
+	}
+	if comments := p.getComments(service.Comments.Leading, service.Comments.Trailing); comments != "" {
+		descs = append(descs, comments)
 	}
 
-	// 解析 request
-	inputMap := p.transField(method.Input, 3)
+	return strings.Join(descs, "\n\n")
+}
+
+func (p *Postman) GetMethodItem(method *protogen.Method) (*Item, error) {
+	httpRules, err := p.getMethodRules(method)
+	if err != nil || len(httpRules) == 0 {
+		return nil, err
+	}
 
 	// 解析注释
-	desc, header := p.getMethodDescAndHeader(method.Comments.Leading)
+	desc, header := p.getMethodDescAndHeader(method.Comments.Leading, method.Comments.Trailing)
+	name := method.GoName
+	if p.ItemName == ITEM_NAME_SUMMARY && desc != "" {
+		name = strings.SplitN(desc, "\n", 2)[0]
+	}
+
+	if len(httpRules) == 1 {
+		return p.GetBindingItem(name, desc, method, 0, httpRules[0], header)
+	}
+
+	// 有 additional_bindings 时，每个 binding 生成一个请求，放在以方法命名的目录下
+	var methodItem = &Item{
+		Name:        name,
+		Description: desc,
+	}
 
-	var methodItem = &Item{}
-	if requestMethod == "POST" {
-		raw, err := json.MarshalIndent(inputMap, "", "    ")
+	for i, binding := range httpRules {
+		requestMethod, urlHost := p.getHttpPattern(binding)
+		bindingItem, err := p.GetBindingItem(requestMethod+" "+urlHost, desc, method, i, binding, header)
 		if err != nil {
 			return nil, err
 		}
 
-		methodItem = &Item{
-			Name: method.GoName + "(" + desc + ")",
-			Request: &Request{
-				Method: requestMethod,
-				Header: header,
-				Body: &Body{
-					Mode:    "raw",
-					Raw:     string(raw),
-					Options: &Options{Raw: &Raw{Language: "json"}},
-				},
-				URL: &URL{
-					Raw:  "{{domain}}" + urlHost,
-					Host: []string{"{{domain}}"},
-					Path: strings.Split(strings.TrimPrefix(urlHost, "/"), "/"),
-				},
+		methodItem.Item = append(methodItem.Item, bindingItem)
+	}
+
+	return methodItem, nil
+}
+
+// GetBindingItem returns the request of the index-th http binding of method
+func (p *Postman) GetBindingItem(name, desc string, method *protogen.Method, index int, httpRule *annotations.HttpRule, header []*Header) (*Item, error) {
+	binding, err := p.getBinding(method, httpRule)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析 request
+	inputObject := p.transField(method.Input, nil)
+
+	// 变量名带上方法的全名和 binding 的序号，不同请求的示例值互不覆盖
+	namespace := string(method.Desc.FullName())
+	if index > 0 {
+		namespace += "." + strconv.Itoa(index)
+	}
+
+	// path 模板中捕获的字段作为 postman 的 path variable，不再出现在 body/query 中
+	var variables, collectionVariables []*Variable
+	paths := binding.GetPaths(func(variable *BindingVariable, verb bool) string {
+		value := variable.Segment.Pattern
+		if sample, ok := p.takeField(inputObject, variable.Fields); ok && value == "" {
+			value = fmt.Sprintf("%v", sample)
+		}
+
+		// postman 把 : 之后的整段作为 path variable 的名称，带 :verb 的最后一段改为引用 collection 的变量
+		if verb {
+			key := namespace + "." + variable.Key
+			collectionVariables = append(collectionVariables, &Variable{
+				Key:         key,
+				Value:       value,
+				Description: p.fieldDescription(variable.Fields[len(variable.Fields)-1]),
+			})
+			return "{{" + key + "}}"
+		}
+
+		variables = append(variables, &Variable{
+			Key:         variable.Key,
+			Value:       value,
+			Description: p.fieldDescription(variable.Fields[len(variable.Fields)-1]),
+		})
+		return ":" + variable.Key
+	})
+
+	var bindingItem = &Item{
+		Name: name,
+		Request: &Request{
+			Method: binding.Method,
+			Header: header,
+			URL: &URL{
+				Raw:      p.BaseURL + "/" + strings.Join(paths, "/"),
+				Host:     []string{p.BaseURL},
+				Path:     paths,
+				Variable: variables,
 			},
+		},
+		namespace: namespace,
+		variables: collectionVariables,
+	}
+
+	var bodyValue interface{}
+	var bodyMessage *protogen.Message
+	switch {
+	case binding.BodyAll:
+		bodyValue, bodyMessage, inputObject = inputObject, method.Input, nil
+	case binding.Body != nil:
+		bodyValue, _ = p.takeField(inputObject, binding.Body)
+		bodyMessage = binding.Body[0].Message
+	}
+
+	// 请求说明：方法注释、rpc 信息、body 字段说明、oneof 说明
+	var descs []string
+	if desc != "" {
+		descs = append(descs, desc)
+	}
+	descs = append(descs, p.getMethodInfo(method, httpRule))
+	if bodyObject, ok := bodyValue.(*Object); ok && bodyMessage != nil {
+		descs = append(descs, "#### Body\n\n| Field | Type | Description |\n| --- | --- | --- |\n"+
+			strings.Join(p.getFieldRows(bodyMessage, bodyObject, ""), "\n"))
+	}
+	if oneofDescs := p.getOneofDescs(method.Input, "", map[protoreflect.FullName]bool{}); len(oneofDescs) > 0 {
+		descs = append(descs, strings.Join(oneofDescs, "\n"))
+	}
+	bindingItem.Request.Description = strings.Join(descs, "\n\n")
+
+	if bodyValue != nil {
+		raw, err := json.MarshalIndent(bodyValue, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+
+		bindingItem.Request.Body = &Body{
+			Mode:    "raw",
+			Raw:     string(raw),
+			Options: &Options{Raw: &Raw{Language: "json"}},
 		}
 	}
 
-	if requestMethod == "GET" {
+	if querys := p.transParmas(method.Input, inputObject, ""); len(querys) > 0 {
 		var rawParams string
-		var querys = p.transParmas(inputMap)
 		for _, query := range querys {
 			rawParams += query.Key + "=" + query.Value + "&"
 		}
 		rawParams = "?" + strings.TrimSuffix(rawParams, "&")
 
-		methodItem = &Item{
-			Name: method.GoName + "(" + desc + ")",
-			Request: &Request{
-				Method: requestMethod,
-				Header: header,
-				URL: &URL{
-					Raw:   "{{domain}}" + urlHost + rawParams,
-					Host:  []string{"{{domain}}"},
-					Path:  strings.Split(strings.TrimPrefix(urlHost, "/"), "/"),
-					Query: querys,
-				},
+		bindingItem.Request.URL.Raw += rawParams
+		bindingItem.Request.URL.Query = querys
+	}
+
+	// 根据 output 生成保存的响应示例，HEAD 的响应没有 body
+	if binding.Method != "HEAD" {
+		response, err := p.getResponse(method, httpRule, bindingItem.Request)
+		if err != nil {
+			return nil, err
+		}
+		bindingItem.Response = []*Response{response}
+	}
+
+	// 校验响应的测试脚本
+	if p.Tests {
+		event, err := p.getTestEvent(method, httpRule)
+		if err != nil {
+			return nil, err
+		}
+		bindingItem.Event = []*Event{event}
+	}
+
+	return bindingItem, nil
+}
+
+// getTestEvent returns the test script asserting a 2xx status and validating the response against the output schema
+func (p *Postman) getTestEvent(method *protogen.Method, httpRule *annotations.HttpRule) (*Event, error) {
+	var event = &Event{
+		Listen: "test",
+		Script: &Script{
+			Type: "text/javascript",
+			Exec: []string{
+				`pm.test("Status code is 2xx", function () {`,
+				`    pm.response.to.be.success;`,
+				`});`,
 			},
+		},
+	}
+
+	// server streaming 的响应为多条 json，HEAD 的响应没有 body，不校验 schema
+	if requestMethod, _ := p.getHttpPattern(httpRule); method.Desc.IsStreamingServer() || requestMethod == "HEAD" {
+		return event, nil
+	}
+
+	name := string(method.Output.Desc.FullName())
+	schema := p.RootSchema(method.Output)
+	if responseBody := httpRule.GetResponseBody(); responseBody != "" {
+		fields, err := p.getFields(method.Output, []string{responseBody})
+		if err != nil {
+			return nil, fmt.Errorf("response_body %q: %v", responseBody, err)
+		}
+		name += "." + responseBody
+
+		defs := NewSchemaDefs(DEFS_PREFIX)
+		schema = p.fieldSchema(fields[0], defs)
+		if len(defs.Defs.Keys()) > 0 {
+			schema.Defs = defs.Defs
 		}
 	}
 
-	return methodItem, nil
+	raw, err := json.MarshalIndent(schema, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	event.Script.Exec = append(event.Script.Exec, "")
+	event.Script.Exec = append(event.Script.Exec, strings.Split("var schema = "+string(raw)+";", "\n")...)
+	event.Script.Exec = append(event.Script.Exec, "",
+		`pm.test("Response matches the schema of `+name+`", function () {`,
+		`    pm.response.to.have.jsonSchema(schema);`,
+		`});`,
+	)
+
+	return event, nil
 }
 
-func (p Postman) transField(message *protogen.Message, recursion uint32) map[string]interface{} {
-	var messageMap = make(map[string]interface{})
+// getResponse returns the saved response example rendered from the output message
+func (p *Postman) getResponse(method *protogen.Method, httpRule *annotations.HttpRule, request *Request) (*Response, error) {
+	outputObject := p.transField(method.Output, nil)
+	var outputValue interface{} = outputObject
+	if value, ok := p.wellKnownValue(method.Output, nil); ok {
+		outputValue = value
+	}
+
+	// response_body 指定的字段作为响应的 body
+	if responseBody := httpRule.GetResponseBody(); responseBody != "" {
+		fields, err := p.getFields(method.Output, []string{responseBody})
+		if err != nil {
+			return nil, fmt.Errorf("response_body %q: %v", responseBody, err)
+		}
+		outputValue, _ = p.takeField(outputObject, fields)
+	}
+
+	// 未展开的 message 不是合法的响应，从示例中去掉，保存的响应才能通过测试脚本的 schema 校验
+	outputValue = p.omitTruncated(outputValue)
+
+	// grpc-gateway 的 server streaming 响应的每条消息为 {"result": ...}
+	if method.Desc.IsStreamingServer() {
+		var resultObject = NewObject()
+		resultObject.Set("result", outputValue)
+		outputValue = resultObject
+	}
+
+	raw, err := json.MarshalIndent(outputValue, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		Name:            "OK",
+		OriginalRequest: request,
+		Status:          "OK",
+		Code:            200,
+		PreviewLanguage: "json",
+		Header: []*Header{{
+			Key:   "Content-Type",
+			Value: "application/json",
+			Type:  "text",
+		}},
+		Body: string(raw),
+	}, nil
+}
+
+// omitTruncated removes the truncated messages from the fields, lists and maps in value
+func (p *Postman) omitTruncated(value interface{}) interface{} {
+	switch v := value.(type) {
+	case Truncated:
+		return nil
+	case *Object:
+		// Delete 会修改 Keys 返回的切片，遍历它的副本
+		for _, key := range append([]string(nil), v.Keys()...) {
+			item, _ := v.Get(key)
+			if _, ok := item.(Truncated); ok {
+				v.Delete(key)
+				continue
+			}
+			v.Set(key, p.omitTruncated(item))
+		}
+	case []interface{}:
+		var list = make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.(Truncated); !ok {
+				list = append(list, p.omitTruncated(item))
+			}
+		}
+		return list
+	}
+
+	return value
+}
+
+// getMethodInfo describes the rpc, its input/output messages and the http binding in markdown
+func (p *Postman) getMethodInfo(method *protogen.Method, httpRule *annotations.HttpRule) string {
+	var input, output = "`" + string(method.Input.Desc.FullName()) + "`", "`" + string(method.Output.Desc.FullName()) + "`"
+	if method.Desc.IsStreamingClient() {
+		input = "stream " + input
+	}
+	if method.Desc.IsStreamingServer() {
+		output = "stream " + output
+	}
+
+	requestMethod, urlHost := p.getHttpPattern(httpRule)
+	binding := "`" + requestMethod + " " + urlHost + "`"
+	if body := httpRule.GetBody(); body != "" {
+		binding += ", body: `" + body + "`"
+	}
+
+	return strings.Join([]string{
+		"- RPC: `" + string(method.Parent.Desc.FullName()) + "/" + string(method.Desc.Name()) + "`",
+		"- Input: " + input,
+		"- Output: " + output,
+		"- HTTP: " + binding,
+	}, "\n")
+}
+
+// getDefaultHttpRule returns the route grpc-gateway generates for unannotated methods: POST /package.Service/Method
+func (p *Postman) getDefaultHttpRule(method *protogen.Method) *annotations.HttpRule {
+	return &annotations.HttpRule{
+		Pattern: &annotations.HttpRule_Post{
+			Post: "/" + string(method.Parent.Desc.FullName()) + "/" + string(method.Desc.Name()),
+		},
+		Body: "*",
+	}
+}
+
+// getHttpPattern returns the request method and url path bound by the http rule
+func (p *Postman) getHttpPattern(httpRule *annotations.HttpRule) (string, string) {
+	switch pattern := httpRule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return "GET", pattern.Get
+	case *annotations.HttpRule_Put:
+		return "PUT", pattern.Put
+	case *annotations.HttpRule_Post:
+		return "POST", pattern.Post
+	case *annotations.HttpRule_Delete:
+		return "DELETE", pattern.Delete
+	case *annotations.HttpRule_Patch:
+		return "PATCH", pattern.Patch
+	case *annotations.HttpRule_Custom:
+		// "*" leaves the method unspecified, fall back to POST
+		kind := strings.ToUpper(pattern.Custom.GetKind())
+		if kind == "" || kind == "*" {
+			kind = "POST"
+		}
+		return kind, pattern.Custom.GetPath()
+	}
+
+	return "POST", ""
+}
+
+// fieldName returns the key of the field in json bodies and query params, depending on the field_names parameter
+func (p *Postman) fieldName(field *protogen.Field) string {
+	if p.FieldNames == FIELD_NAMES_PROTO {
+		// 与 protojson 的 UseProtoNames 一致，group 字段使用 group 的 message 名称
+		if field.Desc.Kind() == protoreflect.GroupKind {
+			return string(field.Message.Desc.Name())
+		}
+		return string(field.Desc.Name())
+	}
+
+	return field.Desc.JSONName()
+}
+
+// getFields resolves a field path of the http rule, eg: user.id, against message
+func (p *Postman) getFields(message *protogen.Message, fieldPath []string) ([]*protogen.Field, error) {
+	var fields []*protogen.Field
+	for _, name := range fieldPath {
+		if message == nil {
+			return nil, fmt.Errorf("field %q is not a message", strings.Join(fieldPath, "."))
+		}
+
+		var found *protogen.Field
+		for _, field := range message.Fields {
+			if string(field.Desc.Name()) == name {
+				found = field
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("no field %q in message %s", name, message.Desc.FullName())
+		}
+
+		fields = append(fields, found)
+		message = found.Message
+	}
+
+	return fields, nil
+}
+
+func (p *Postman) transField(message *protogen.Message, path []protoreflect.FullName) *Object {
+	// path 为正在展开的 message，用于检测循环引用
+	path = append(path[:len(path):len(path)], message.Desc.FullName())
+
+	var messageObject = NewObject()
+	var oneofs = make(map[*protogen.Oneof]bool)
 	for _, field := range message.Fields {
-		fieldName := string(field.Desc.Name())
-
-		switch field.Desc.Kind() {
-		case protoreflect.BoolKind:
-			messageMap[fieldName] = false
-		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Uint32Kind, protoreflect.Int64Kind,
-			protoreflect.Sint64Kind, protoreflect.Uint64Kind, protoreflect.Sfixed32Kind, protoreflect.Fixed32Kind,
-			protoreflect.FloatKind, protoreflect.Sfixed64Kind, protoreflect.Fixed64Kind, protoreflect.DoubleKind:
-			messageMap[fieldName] = 0
-		case protoreflect.EnumKind, protoreflect.StringKind, protoreflect.BytesKind:
-			messageMap[fieldName] = ""
-		case protoreflect.MessageKind:
-			// 防止循环递归
-			if recursion > 0 {
-				messageMap[fieldName] = p.transField(field.Message, recursion-1)
+		// oneof 只生成第一个字段，同时设置多个字段会被服务端拒绝
+		oneof := p.getOneof(field)
+		if oneof != nil && oneofs[oneof] {
+			continue
+		}
+
+		messageObject.Set(p.fieldName(field), p.fieldValue(field, path))
+		if oneof != nil {
+			oneofs[oneof] = true
+		}
+	}
+
+	return messageObject
+}
+
+// getOneof returns the oneof containing the field, proto3 optional fields are treated as normal fields
+func (p *Postman) getOneof(field *protogen.Field) *protogen.Oneof {
+	oneof := field.Oneof
+	if oneof == nil {
+		return nil
+	}
+
+	// proto3 optional 字段对应的 synthetic oneof 不是真正的 oneof
+	if oneof.Desc.IsSynthetic() {
+		return nil
+	}
+
+	return oneof
+}
+
+// getOneofDescs describes the oneofs of message and its nested messages, listing the alternatives of the sample field
+func (p *Postman) getOneofDescs(message *protogen.Message, prefix string, visited map[protoreflect.FullName]bool) []string {
+	if visited[message.Desc.FullName()] {
+		return nil
+	}
+	visited[message.Desc.FullName()] = true
+	defer delete(visited, message.Desc.FullName())
+
+	var descs []string
+	for _, oneof := range message.Oneofs {
+		if p.getOneof(oneof.Fields[0]) == nil {
+			continue
+		}
+
+		var names []string
+		for _, field := range oneof.Fields {
+			names = append(names, "`"+prefix+p.fieldName(field)+"`")
+		}
+		descs = append(descs, fmt.Sprintf("- oneof `%s%s`: only one of %s can be set, the sample uses the first one",
+			prefix, oneof.Desc.Name(), strings.Join(names, ", ")))
+	}
+
+	for _, field := range message.Fields {
+		if field.Message == nil || field.Desc.IsMap() {
+			continue
+		}
+		if _, ok := p.wellKnownValue(field.Message, nil); ok {
+			continue
+		}
+
+		descs = append(descs, p.getOneofDescs(field.Message, prefix+p.fieldName(field)+".", visited)...)
+	}
+
+	return descs
+}
+
+// fieldValue returns the sample value of the field
+func (p *Postman) fieldValue(field *protogen.Field, path []protoreflect.FullName) interface{} {
+	// map 按 protojson 生成 json object: {"key": value}
+	if field.Desc.IsMap() {
+		var mapObject = NewObject()
+		mapObject.Set(p.mapKey(field.Message.Fields[0]), p.singularValue(field.Message.Fields[1], path))
+		return mapObject
+	}
+
+	value := p.singularValue(field, path)
+	if field.Desc.IsList() {
+		value = []interface{}{value}
+	}
+
+	return value
+}
+
+// singularValue returns the sample value of a single element of the field
+func (p *Postman) singularValue(field *protogen.Field, path []protoreflect.FullName) interface{} {
+	// proto2 的 [default = ...]
+	if field.Desc.HasDefault() {
+		return p.defaultValue(field)
+	}
+
+	switch field.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return false
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Uint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Fixed32Kind, protoreflect.FloatKind, protoreflect.DoubleKind:
+		return 0
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Uint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Fixed64Kind:
+		// protojson 把 64 位整数编码为字符串
+		if p.Int64Numbers {
+			return 0
+		}
+		return "0"
+	case protoreflect.EnumKind:
+		if field.Enum.Desc.FullName() == "google.protobuf.NullValue" {
+			return nil
+		}
+		return p.enumValue(field.Enum)
+	case protoreflect.StringKind:
+		return ""
+	case protoreflect.BytesKind:
+		// protojson 把 bytes 编码为 base64
+		return BYTES_SAMPLE
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if value, ok := p.wellKnownValue(field.Message, path); ok {
+			return value
+		}
+
+		// 循环引用的 message 只再展开一层，超过 max_depth 的 message 不再展开
+		name := field.Message.Desc.FullName()
+		var count int
+		for _, n := range path {
+			if n == name {
+				count++
 			}
-		case protoreflect.GroupKind:
-			messageMap[fieldName] = "group"
+		}
+		if count > 1 {
+			return Truncated("(recursive " + name + ")")
+		}
+		if uint32(len(path)) > p.MaxDepth {
+			return Truncated("(max_depth exceeded " + name + ")")
+		}
+
+		return p.transField(field.Message, path)
+	}
+
+	return "other"
+}
+
+// defaultValue returns the proto2 default value of the field in its protojson form
+func (p *Postman) defaultValue(field *protogen.Field) interface{} {
+	value := field.Desc.Default()
+	switch field.Desc.Kind() {
+	case protoreflect.EnumKind:
+		return string(field.Desc.DefaultEnumValue().Name())
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(value.Bytes())
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Uint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Fixed64Kind:
+		if p.Int64Numbers {
+			return value.Interface()
+		}
+		return value.String()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		// protojson 把 NaN 和 Infinity 编码为字符串
+		switch f := value.Float(); {
+		case math.IsNaN(f):
+			return "NaN"
+		case math.IsInf(f, 1):
+			return "Infinity"
+		case math.IsInf(f, -1):
+			return "-Infinity"
+		case field.Desc.Kind() == protoreflect.FloatKind:
+			// 按 float32 编码，否则 0.1 会生成为 0.10000000149011612
+			return float32(f)
 		default:
-			messageMap[fieldName] = "other"
+			return f
+		}
+	}
+
+	return value.Interface()
+}
+
+// mapKey returns the placeholder key of map fields, protojson always encodes map keys as strings
+func (p *Postman) mapKey(keyField *protogen.Field) string {
+	switch keyField.Desc.Kind() {
+	case protoreflect.StringKind:
+		return "key"
+	case protoreflect.BoolKind:
+		return "true"
+	}
+
+	return "0"
+}
+
+// enumValue returns the sample value of enum: the first non-zero value, or the zero value if there is no other
+func (p *Postman) enumValue(enum *protogen.Enum) string {
+	for _, value := range enum.Values {
+		if value.Desc.Number() != 0 {
+			return string(value.Desc.Name())
+		}
+	}
+	if len(enum.Values) > 0 {
+		return string(enum.Values[0].Desc.Name())
+	}
+
+	return ""
+}
+
+// fieldDescription returns the description of the field used by query params and path variables
+func (p *Postman) fieldDescription(field *protogen.Field) string {
+	var desc []string
+	if comments := p.getComments(field.Comments.Leading, field.Comments.Trailing); comments != "" {
+		desc = append(desc, comments)
+	}
+	if field.Desc.Cardinality() == protoreflect.Required {
+		desc = append(desc, "**Required**")
+	}
+	if field.Enum != nil {
+		var values []string
+		for _, value := range field.Enum.Values {
+			values = append(values, "`"+string(value.Desc.Name())+"`")
+		}
+		desc = append(desc, "Enum values: "+strings.Join(values, ", "))
+	}
+
+	return strings.Join(desc, "\n\n")
+}
+
+// getComments joins the comment blocks with a blank line, blank lines and indentation inside a block are kept
+func (p *Postman) getComments(comments ...protogen.Comments) string {
+	var blocks []string
+	for _, comment := range comments {
+		// protoc 保留了 // 之后的空格，每行只去掉这一个空格，保留段落、列表和缩进
+		lines := strings.Split(strings.TrimRight(string(comment), "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(strings.TrimPrefix(line, " "), " \t\r")
 		}
 
-		if field.Desc.IsList() {
-			messageMap[fieldName] = []interface{}{messageMap[fieldName]}
+		if block := strings.Trim(strings.Join(lines, "\n"), "\n"); block != "" {
+			blocks = append(blocks, block)
 		}
 	}
 
-	return messageMap
+	return strings.Join(blocks, "\n\n")
 }
 
-func (p Postman) transParmas(pMap map[string]interface{}) []*Query {
+// fieldType returns the proto type of the field, eg: repeated string, map<string, int32>, test.Message
+func (p *Postman) fieldType(field *protogen.Field) string {
+	if field.Desc.IsMap() {
+		return "map<" + p.fieldType(field.Message.Fields[0]) + ", " + p.fieldType(field.Message.Fields[1]) + ">"
+	}
+
+	var fieldType = field.Desc.Kind().String()
+	switch {
+	case field.Message != nil:
+		fieldType = string(field.Message.Desc.FullName())
+	case field.Enum != nil:
+		fieldType = string(field.Enum.Desc.FullName())
+	}
+	if field.Desc.IsList() {
+		fieldType = "repeated " + fieldType
+	}
+
+	return fieldType
+}
+
+// getFieldRows returns the markdown table rows describing the fields of message present in object, nested messages included
+func (p *Postman) getFieldRows(message *protogen.Message, object *Object, prefix string) []string {
+	var rows []string
+	for _, field := range message.Fields {
+		fieldName := p.fieldName(field)
+		value, ok := object.Get(fieldName)
+		if !ok {
+			continue
+		}
+
+		desc := strings.NewReplacer("\n", "<br>", "|", "\\|").Replace(p.fieldDescription(field))
+		rows = append(rows, fmt.Sprintf("| `%s%s` | %s | %s |", prefix, fieldName, p.fieldType(field), desc))
+
+		if field.Desc.IsMap() {
+			continue
+		}
+		if list, ok := value.([]interface{}); ok && len(list) > 0 {
+			value, fieldName = list[0], fieldName+"[]"
+		}
+		if sonObject, ok := value.(*Object); ok {
+			if _, ok := p.wellKnownValue(field.Message, nil); !ok {
+				rows = append(rows, p.getFieldRows(field.Message, sonObject, prefix+fieldName+".")...)
+			}
+		}
+	}
+
+	return rows
+}
+
+// takeField removes the field addressed by fields from object and returns its value
+func (p *Postman) takeField(object *Object, fields []*protogen.Field) (interface{}, bool) {
+	for i, field := range fields {
+		name := p.fieldName(field)
+		value, ok := object.Get(name)
+		if !ok {
+			return nil, false
+		}
+
+		if i == len(fields)-1 {
+			object.Delete(name)
+			return value, true
+		}
+
+		if object, ok = value.(*Object); !ok {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+// transParmas flattens object into query params, following the field order of message
+func (p *Postman) transParmas(message *protogen.Message, object *Object, prefix string) []*Query {
 	var q []*Query
-	for k, v := range pMap {
-		valueOf := reflect.ValueOf(v)
-		if valueOf.Kind() == reflect.Map {
-			var sonMap = make(map[string]interface{})
-			keys := valueOf.MapKeys()
-			for _, key := range keys {
-				value := valueOf.MapIndex(key)
-				sonMap[k+"."+fmt.Sprintf("%v", key)] = value
+	if object == nil {
+		return q
+	}
+
+	for _, field := range message.Fields {
+		fieldName := p.fieldName(field)
+		value, ok := object.Get(fieldName)
+		if !ok {
+			continue
+		}
+
+		switch v := value.(type) {
+		case Truncated:
+			continue
+		case *Object:
+			if field.Desc.IsMap() {
+				// map 字段以 key[map_key]=value 的形式传递
+				for _, mapKey := range v.Keys() {
+					mapValue, _ := v.Get(mapKey)
+					if !p.isQueryValue(mapValue) {
+						continue
+					}
+
+					q = append(q, &Query{
+						Key:         prefix + fieldName + "[" + mapKey + "]",
+						Value:       fmt.Sprintf("%v", mapValue),
+						Description: p.fieldDescription(field.Message.Fields[1]),
+					})
+				}
+				continue
 			}
 
-			q = append(q, p.transParmas(sonMap)...)
-		} else {
+			q = append(q, p.transParmas(field.Message, v, prefix+fieldName+".")...)
+		case []interface{}:
+			// repeated 字段以 key=v1&key=v2 的形式传递，query 中不支持 repeated message
+			for _, item := range v {
+				if !p.isQueryValue(item) {
+					continue
+				}
+
+				q = append(q, &Query{
+					Key:         prefix + fieldName,
+					Value:       fmt.Sprintf("%v", item),
+					Description: p.fieldDescription(field),
+				})
+			}
+		default:
 			q = append(q, &Query{
-				Key:   k,
-				Value: fmt.Sprintf("%v", v),
+				Key:         prefix + fieldName,
+				Value:       fmt.Sprintf("%v", value),
+				Description: p.fieldDescription(field),
 			})
 		}
 	}
@@ -313,14 +1161,23 @@ func (p Postman) transParmas(pMap map[string]interface{}) []*Query {
 	return q
 }
 
-func (p Postman) getMethodDescAndHeader(commentLeading protogen.Comments) (string, []*Header) {
-	var desc string
+// isQueryValue reports whether value can be passed as a query param, messages are not supported
+func (p *Postman) isQueryValue(value interface{}) bool {
+	switch value.(type) {
+	case *Object, Truncated:
+		return false
+	}
+
+	return true
+}
+
+func (p *Postman) getMethodDescAndHeader(comments ...protogen.Comments) (string, []*Header) {
+	var desc []string
 	var header []*Header
 
-	comments := strings.Split(string(commentLeading), "\n")
-	for _, comment := range comments {
-		commentArr := strings.Split(strings.TrimSpace(comment), " ")
-		if commentArr[0] == COMMENTS_HEADER && len(commentArr) >= 2 {
+	for _, comment := range strings.Split(p.getComments(comments...), "\n") {
+		commentArr := strings.Fields(comment)
+		if len(commentArr) >= 2 && commentArr[0] == COMMENTS_HEADER {
 			key := strings.TrimPrefix(commentArr[1], "*")
 			header = append(header, &Header{
 				Key:   GRPC_HEADER_PREFIX + key,
@@ -328,9 +1185,9 @@ func (p Postman) getMethodDescAndHeader(commentLeading protogen.Comments) (strin
 				Type:  "text",
 			})
 		} else {
-			desc += comment
+			desc = append(desc, comment)
 		}
 	}
 
-	return desc, header
+	return strings.Trim(strings.Join(desc, "\n"), "\n"), header
 }
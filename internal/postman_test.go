@@ -0,0 +1,212 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/MaiBeng/protoc-gen-postman/google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestGetBindingItemMethod(t *testing.T) {
+	tests := []struct {
+		rule   *annotations.HttpRule
+		method string
+		body   bool
+	}{
+		{rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/items"}}, method: "GET"},
+		{rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/items"}, Body: "*"}, method: "POST", body: true},
+		{rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Put{Put: "/v1/items"}, Body: "*"}, method: "PUT", body: true},
+		{rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Delete{Delete: "/v1/items"}}, method: "DELETE"},
+		{rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Patch{Patch: "/v1/items"}, Body: "*"}, method: "PATCH", body: true},
+		{
+			rule: &annotations.HttpRule{Pattern: &annotations.HttpRule_Custom{
+				Custom: &annotations.CustomHttpPattern{Kind: "HEAD", Path: "/v1/items"},
+			}},
+			method: "HEAD",
+		},
+	}
+
+	plugin := newTestPlugin(t, testFile("a.proto", "a", testMethod("Get", nil)))
+	method := plugin.Files[0].Services[0].Methods[0]
+	p := NewPostman()
+	for _, tt := range tests {
+		item, err := p.GetBindingItem("Get", "", method, 0, tt.rule, nil)
+		if err != nil {
+			t.Errorf("GetBindingItem(%v) error: %v", tt.rule, err)
+			continue
+		}
+
+		request := item.Request
+		if path := strings.Join(request.URL.Path, "/"); request.Method != tt.method || path != "v1/items" || (request.Body != nil) != tt.body {
+			t.Errorf("GetBindingItem(%v) = %s %s body=%v, want %s v1/items body=%v",
+				tt.rule, request.Method, path, request.Body != nil, tt.method, tt.body)
+		}
+		// HEAD 的响应没有 body，不保存响应示例
+		if (item.Response == nil) != (tt.method == "HEAD") {
+			t.Errorf("GetBindingItem(%v) responses = %d", tt.rule, len(item.Response))
+		}
+	}
+}
+
+func TestGetMethodItemAdditionalBindings(t *testing.T) {
+	plugin := newTestPlugin(t, testFile("a.proto", "a",
+		testMethod("Get", &annotations.HttpRule{
+			Pattern: &annotations.HttpRule_Get{Get: "/v1/{name}"},
+			AdditionalBindings: []*annotations.HttpRule{
+				{Pattern: &annotations.HttpRule_Get{Get: "/v1/children/{child.id}"}},
+				{Pattern: &annotations.HttpRule_Post{Post: "/v1/items:get"}, Body: "*"},
+			},
+		}),
+		testMethod("List", &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/items"}}),
+	))
+	methods := plugin.Files[0].Services[0].Methods
+	p := NewPostman()
+
+	// 有 additional_bindings 时，每个 binding 是以方法命名的目录下的一个请求
+	item, err := p.GetMethodItem(methods[0])
+	if err != nil {
+		t.Fatalf("GetMethodItem error: %v", err)
+	}
+	if item.Name != "Get" || item.Request != nil {
+		t.Fatalf("GetMethodItem = %s request=%v, want the folder Get", item.Name, item.Request)
+	}
+	want := []struct {
+		name, method, path string
+	}{
+		{"GET /v1/{name}", "GET", "v1/:name"},
+		{"GET /v1/children/{child.id}", "GET", "v1/children/:child.id"},
+		{"POST /v1/items:get", "POST", "v1/items:get"},
+	}
+	if len(item.Item) != len(want) {
+		t.Fatalf("GetMethodItem has %d requests, want %d", len(item.Item), len(want))
+	}
+	for i, w := range want {
+		request := item.Item[i].Request
+		if path := strings.Join(request.URL.Path, "/"); item.Item[i].Name != w.name || request.Method != w.method || path != w.path {
+			t.Errorf("binding %d = %q %s %s, want %q %s %s", i, item.Item[i].Name, request.Method, path, w.name, w.method, w.path)
+		}
+	}
+
+	// 只有一个 binding 时直接生成请求
+	item, err = p.GetMethodItem(methods[1])
+	if err != nil {
+		t.Fatalf("GetMethodItem error: %v", err)
+	}
+	if item.Name != "List" || item.Request == nil || item.Item != nil {
+		t.Errorf("GetMethodItem = %s request=%v items=%d, want the request List", item.Name, item.Request, len(item.Item))
+	}
+}
+
+// queryKeys returns the keys of the query params of request
+func queryKeys(request *Request) []string {
+	var keys []string
+	for _, query := range request.URL.Query {
+		keys = append(keys, query.Key)
+	}
+
+	return keys
+}
+
+func TestGetBindingItemPathVariables(t *testing.T) {
+	plugin := newTestPlugin(t, testFile("a.proto", "a",
+		testMethod("Get", &annotations.HttpRule{
+			Pattern: &annotations.HttpRule_Get{Get: "/v1/{child.id}/items/{name=shelves/*}:cancel"},
+			AdditionalBindings: []*annotations.HttpRule{
+				{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name}:cancel"}},
+			},
+		}),
+		testMethod("List", &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name}:cancel"}}),
+	))
+	p := NewPostman()
+	collection, err := p.GetCollection("api", plugin.Files)
+	if err != nil {
+		t.Fatalf("GetCollection error: %v", err)
+	}
+	items := collection.Item[0].Item[0].Item // package => service => method
+
+	// path 变量从 query 中去掉，带 :verb 的变量引用以请求命名的 collection 变量
+	request := items[0].Item[0].Request
+	if want := []string{"v1", ":child.id", "items", "{{a.Service.Get.name}}:cancel"}; !reflect.DeepEqual(request.URL.Path, want) {
+		t.Errorf("path = %q, want %q", request.URL.Path, want)
+	}
+	if len(request.URL.Variable) != 1 || request.URL.Variable[0].Key != "child.id" {
+		t.Errorf("path variables = %+v, want child.id", request.URL.Variable)
+	}
+	if want := []string{"child.note", "page"}; !reflect.DeepEqual(queryKeys(request), want) {
+		t.Errorf("query = %q, want %q", queryKeys(request), want)
+	}
+
+	if want := []string{"v1", "{{a.Service.Get.1.name}}:cancel"}; !reflect.DeepEqual(items[0].Item[1].Request.URL.Path, want) {
+		t.Errorf("path = %q, want %q", items[0].Item[1].Request.URL.Path, want)
+	}
+	if want := []string{"v1", "{{a.Service.List.name}}:cancel"}; !reflect.DeepEqual(items[1].Request.URL.Path, want) {
+		t.Errorf("path = %q, want %q", items[1].Request.URL.Path, want)
+	}
+
+	// 每个请求的示例值互不覆盖
+	var variables = make(map[string]string)
+	for _, variable := range collection.Variable {
+		variables[variable.Key] = variable.Value
+	}
+	want := map[string]string{
+		"a.Service.Get.name":   "shelves/*",
+		"a.Service.Get.1.name": "",
+		"a.Service.List.name":  "",
+	}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("collection variables = %v, want %v", variables, want)
+	}
+}
+
+func TestGetBindingItemBody(t *testing.T) {
+	tests := []struct {
+		rule  *annotations.HttpRule
+		body  []string // keys of the body object
+		query []string
+	}{
+		{
+			rule:  &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name}"}, Body: "*"},
+			body:  []string{"child", "page"},
+			query: nil,
+		},
+		{
+			rule:  &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name}"}, Body: "child"},
+			body:  []string{"id", "note"},
+			query: []string{"page"},
+		},
+		{
+			rule:  &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/{name}"}},
+			query: []string{"child.id", "child.note", "page"},
+		},
+	}
+
+	plugin := newTestPlugin(t, testFile("a.proto", "a", testMethod("Get", nil)))
+	method := plugin.Files[0].Services[0].Methods[0]
+	p := NewPostman()
+	for _, tt := range tests {
+		item, err := p.GetBindingItem("Get", "", method, 0, tt.rule, nil)
+		if err != nil {
+			t.Errorf("GetBindingItem(%v) error: %v", tt.rule, err)
+			continue
+		}
+
+		var body []string
+		if item.Request.Body != nil {
+			var object map[string]interface{}
+			if err := json.Unmarshal([]byte(item.Request.Body.Raw), &object); err != nil {
+				t.Errorf("GetBindingItem(%v) body %q: %v", tt.rule, item.Request.Body.Raw, err)
+				continue
+			}
+			for key := range object {
+				body = append(body, key)
+			}
+			sort.Strings(body)
+		}
+		if !reflect.DeepEqual(body, tt.body) || !reflect.DeepEqual(queryKeys(item.Request), tt.query) {
+			t.Errorf("GetBindingItem(%v) body = %q query = %q, want %q %q", tt.rule, body, queryKeys(item.Request), tt.body, tt.query)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"encoding/json"
+	"path"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	JSON_SCHEMA_DRAFT  = "https://json-schema.org/draft/2020-12/schema"
+	JSON_SCHEMA_DIR    = "schemas"
+	JSON_SCHEMA_SUFFIX = ".schema.json"
+	DEFS_PREFIX        = "#/$defs/"
+)
+
+// Schema is a json schema (draft 2020-12) describing the protojson form of messages
+type Schema struct {
+	Schema               string        `json:"$schema,omitempty"`
+	ID                   string        `json:"$id,omitempty"`
+	Ref                  string        `json:"$ref,omitempty"`
+	Title                string        `json:"title,omitempty"`
+	Description          string        `json:"description,omitempty"`
+	Type                 interface{}   `json:"type,omitempty"` // string or []string
+	Format               string        `json:"format,omitempty"`
+	Pattern              string        `json:"pattern,omitempty"`
+	ContentEncoding      string        `json:"contentEncoding,omitempty"`
+	Enum                 []interface{} `json:"enum,omitempty"`
+	AnyOf                []*Schema     `json:"anyOf,omitempty"`
+	Properties           *Object       `json:"properties,omitempty"`
+	Required             []string      `json:"required,omitempty"`
+	Items                *Schema       `json:"items,omitempty"`
+	AdditionalProperties *Schema       `json:"additionalProperties,omitempty"`
+	Defs                 *Object       `json:"$defs,omitempty"`
+}
+
+// SchemaDefs collects the schemas of the messages referenced by $ref, keyed by the message full name
+type SchemaDefs struct {
+	Prefix        string // prefix of the $ref, eg: #/$defs/
+	Defs          *Object
+	NumberFormats bool // 生成 int32、double 等 OpenAPI 的 format，json schema 的校验器不认识这些 format
+}
+
+func NewSchemaDefs(prefix string) *SchemaDefs {
+	return &SchemaDefs{
+		Prefix: prefix,
+		Defs:   NewObject(),
+	}
+}
+
+// GenerateSchemas writes a json schema file for every input and output message of the methods in files
+func (p *Postman) GenerateSchemas(plugin *protogen.Plugin, files []*protogen.File) error {
+	var written = make(map[protoreflect.FullName]bool)
+	for _, file := range files {
+		for _, service := range file.Services {
+			for _, method := range service.Methods {
+				for _, message := range []*protogen.Message{method.Input, method.Output} {
+					if written[message.Desc.FullName()] {
+						continue
+					}
+					written[message.Desc.FullName()] = true
+
+					if err := p.GenerateSchema(plugin, file, message); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// GenerateSchema writes the json schema of message, nested messages are put in $defs
+func (p *Postman) GenerateSchema(plugin *protogen.Plugin, file *protogen.File, message *protogen.Message) error {
+	name := string(message.Desc.FullName()) + JSON_SCHEMA_SUFFIX
+	g := plugin.NewGeneratedFile(path.Join(path.Dir(p.Filename), JSON_SCHEMA_DIR, name), file.GoImportPath)
+
+	schema := p.RootSchema(message)
+	schema.Schema = JSON_SCHEMA_DRAFT
+	schema.ID = name
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	g.P(string(out))
+
+	return nil
+}
+
+// RootSchema returns a self-contained schema of message, with the referenced messages in its $defs
+func (p *Postman) RootSchema(message *protogen.Message) *Schema {
+	defs := NewSchemaDefs(DEFS_PREFIX)
+	schema := p.messageSchema(message, defs)
+	schema.Title = string(message.Desc.FullName())
+	if len(defs.Defs.Keys()) > 0 {
+		schema.Defs = defs.Defs
+	}
+
+	return schema
+}
+
+// messageSchema returns the schema of message, a $ref to defs unless message is a well-known type
+func (p *Postman) messageSchema(message *protogen.Message, defs *SchemaDefs) *Schema {
+	if schema, ok := p.wellKnownSchema(message, defs); ok {
+		return schema
+	}
+
+	name := string(message.Desc.FullName())
+	if _, ok := defs.Defs.Get(name); !ok {
+		// 先占位，递归引用自身的 message 直接使用 $ref
+		defs.Defs.Set(name, &Schema{})
+		defs.Defs.Set(name, p.messageDef(message, defs))
+	}
+
+	return &Schema{Ref: defs.Prefix + name}
+}
+
+// messageDef returns the object schema of the fields of message
+func (p *Postman) messageDef(message *protogen.Message, defs *SchemaDefs) *Schema {
+	var schema = &Schema{
+		Type:        "object",
+		Description: p.getComments(message.Comments.Leading, message.Comments.Trailing),
+		Properties:  NewObject(),
+	}
+	for _, field := range message.Fields {
+		fieldSchema := p.fieldSchema(field, defs)
+		fieldSchema.Description = p.getComments(field.Comments.Leading, field.Comments.Trailing)
+		schema.Properties.Set(p.fieldName(field), fieldSchema)
+		if field.Desc.Cardinality() == protoreflect.Required {
+			schema.Required = append(schema.Required, p.fieldName(field))
+		}
+	}
+
+	return schema
+}
+
+// fieldSchema returns the schema of the field
+func (p *Postman) fieldSchema(field *protogen.Field, defs *SchemaDefs) *Schema {
+	// map 在 protojson 中的 key 都是字符串
+	if field.Desc.IsMap() {
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: p.singularSchema(field.Message.Fields[1], defs),
+		}
+	}
+
+	schema := p.singularSchema(field, defs)
+	if field.Desc.IsList() {
+		return &Schema{
+			Type:  "array",
+			Items: schema,
+		}
+	}
+
+	// grpc-gateway 默认的 EmitUnpopulated 把未设置的 message 字段编码为 null
+	if field.Message != nil && field.Message.Desc.FullName() != "google.protobuf.Value" {
+		schema = &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+	}
+
+	return schema
+}
+
+// singularSchema returns the schema of a single element of the field
+func (p *Postman) singularSchema(field *protogen.Field, defs *SchemaDefs) *Schema {
+	switch field.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{Type: "integer", Format: p.numberFormat(defs, "int32")}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Schema{Type: "integer", Format: p.numberFormat(defs, "uint32")}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// protojson 把 64 位整数编码为字符串，也接受数字
+		return &Schema{Type: []string{"string", "integer"}, Format: p.numberFormat(defs, "int64")}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{Type: []string{"string", "integer"}, Format: p.numberFormat(defs, "uint64")}
+	case protoreflect.FloatKind:
+		// NaN 和 Infinity 编码为字符串
+		return &Schema{Type: []string{"number", "string"}, Format: p.numberFormat(defs, "float")}
+	case protoreflect.DoubleKind:
+		return &Schema{Type: []string{"number", "string"}, Format: p.numberFormat(defs, "double")}
+	case protoreflect.StringKind:
+		return &Schema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &Schema{Type: "string", ContentEncoding: "base64"}
+	case protoreflect.EnumKind:
+		if field.Enum.Desc.FullName() == "google.protobuf.NullValue" {
+			return &Schema{Type: "null"}
+		}
+
+		var values []interface{}
+		for _, value := range field.Enum.Values {
+			values = append(values, string(value.Desc.Name()))
+		}
+		return &Schema{Type: "string", Enum: values}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return p.messageSchema(field.Message, defs)
+	}
+
+	return &Schema{}
+}
+
+// numberFormat returns format when defs keeps the number formats, otherwise empty
+func (p *Postman) numberFormat(defs *SchemaDefs, format string) string {
+	if !defs.NumberFormats {
+		return ""
+	}
+
+	return format
+}
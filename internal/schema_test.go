@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/MaiBeng/protoc-gen-postman/google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestGenerateSchemas(t *testing.T) {
+	plugin := newTestPlugin(t, testFile("a.proto", "a",
+		testMethod("Get", &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name}"}}),
+		testMethod("List", &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/items"}}),
+	))
+	p := NewPostman()
+	if err := p.GenerateSchemas(plugin, plugin.Files); err != nil {
+		t.Fatalf("GenerateSchemas error: %v", err)
+	}
+
+	// 多个方法共用的 message 只写一次
+	var schemas = make(map[string]map[string]interface{})
+	var names []string
+	for _, file := range plugin.Response().File {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(file.GetContent()), &schema); err != nil {
+			t.Fatalf("GenerateSchemas wrote an invalid schema %s: %v", file.GetName(), err)
+		}
+		schemas[file.GetName()] = schema
+		names = append(names, file.GetName())
+	}
+	if want := []string{"schemas/a.Request.schema.json", "schemas/a.Response.schema.json"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("GenerateSchemas wrote %q, want %q", names, want)
+	}
+
+	request := schemas["schemas/a.Request.schema.json"]
+	for key, want := range map[string]interface{}{
+		"$schema": JSON_SCHEMA_DRAFT,
+		"$id":     "a.Request.schema.json",
+		"title":   "a.Request",
+		"$ref":    DEFS_PREFIX + "a.Request",
+	} {
+		if request[key] != want {
+			t.Errorf("request schema %s = %v, want %v", key, request[key], want)
+		}
+	}
+	defs, _ := request["$defs"].(map[string]interface{})
+	if _, ok := defs["a.Child"]; !ok {
+		t.Errorf("request schema $defs = %v, want a.Child", defs)
+	}
+
+	// 递归的 message 引用自身，未设置时为 null
+	defs, _ = schemas["schemas/a.Response.schema.json"]["$defs"].(map[string]interface{})
+	def, _ := defs["a.Response"].(map[string]interface{})
+	properties, _ := def["properties"].(map[string]interface{})
+	want := map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"$ref": DEFS_PREFIX + "a.Response"},
+			map[string]interface{}{"type": "null"},
+		},
+	}
+	if !reflect.DeepEqual(properties["parent"], want) {
+		t.Errorf("response schema parent = %v, want %v", properties["parent"], want)
+	}
+}
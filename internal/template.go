@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathTemplate is a parsed google.api.http path template, eg: /v1/{name=projects/*/items/*}:cancel
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	FieldPath = IDENT { "." IDENT } ;
+//	Verb     = ":" LITERAL ;
+type PathTemplate struct {
+	Segments  []*PathSegment
+	Verb      string
+	Variables []*PathSegment
+}
+
+type PathSegment struct {
+	Literal   string   // literal segment, empty when is variable
+	FieldPath []string // captured field path, empty when is literal
+	Pattern   string   // segments matched by the variable, empty means "*"
+}
+
+func (s *PathSegment) IsVariable() bool {
+	return len(s.FieldPath) > 0
+}
+
+func parsePathTemplate(path string) (*PathTemplate, error) {
+	if path == "" {
+		return &PathTemplate{}, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path template %q must start with /", path)
+	}
+
+	var template = &PathTemplate{}
+	var depth int
+	var segment strings.Builder
+	var flush = func() error {
+		text := segment.String()
+		segment.Reset()
+
+		if !strings.HasPrefix(text, "{") {
+			template.Segments = append(template.Segments, &PathSegment{Literal: text})
+			return nil
+		}
+
+		variable := strings.TrimSuffix(strings.TrimPrefix(text, "{"), "}")
+		fieldPath, pattern := variable, ""
+		if i := strings.Index(variable, "="); i >= 0 {
+			fieldPath, pattern = variable[:i], variable[i+1:]
+		}
+		if fieldPath == "" {
+			return fmt.Errorf("path template %q has an empty variable", path)
+		}
+
+		s := &PathSegment{
+			FieldPath: strings.Split(fieldPath, "."),
+			Pattern:   pattern,
+		}
+		template.Segments = append(template.Segments, s)
+		template.Variables = append(template.Variables, s)
+		return nil
+	}
+
+	rest := path[1:]
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == '{':
+			if depth > 0 || segment.Len() > 0 {
+				return nil, fmt.Errorf("path template %q has an unexpected {", path)
+			}
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return nil, fmt.Errorf("path template %q has an unexpected }", path)
+			}
+			depth--
+		case c == '/' && depth == 0:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		case c == ':' && depth == 0 && !strings.Contains(rest[i:], "/"):
+			// 最后一段的 :verb
+			template.Verb = rest[i+1:]
+			i = len(rest)
+			continue
+		}
+
+		segment.WriteByte(c)
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("path template %q has an unclosed {", path)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
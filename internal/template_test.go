@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		path string
+		want *PathTemplate
+	}{
+		{
+			path: "",
+			want: &PathTemplate{},
+		},
+		{
+			path: "/v1/users",
+			want: &PathTemplate{
+				Segments: []*PathSegment{{Literal: "v1"}, {Literal: "users"}},
+			},
+		},
+		{
+			path: "/v1/users/{user.id}",
+			want: &PathTemplate{
+				Segments: []*PathSegment{
+					{Literal: "v1"},
+					{Literal: "users"},
+					{FieldPath: []string{"user", "id"}},
+				},
+				Variables: []*PathSegment{{FieldPath: []string{"user", "id"}}},
+			},
+		},
+		{
+			path: "/v1/{name=projects/*/items/*}",
+			want: &PathTemplate{
+				Segments: []*PathSegment{
+					{Literal: "v1"},
+					{FieldPath: []string{"name"}, Pattern: "projects/*/items/*"},
+				},
+				Variables: []*PathSegment{{FieldPath: []string{"name"}, Pattern: "projects/*/items/*"}},
+			},
+		},
+		{
+			path: "/v1/files/**",
+			want: &PathTemplate{
+				Segments: []*PathSegment{{Literal: "v1"}, {Literal: "files"}, {Literal: "**"}},
+			},
+		},
+		{
+			path: "/v1/{name=files/**}",
+			want: &PathTemplate{
+				Segments: []*PathSegment{
+					{Literal: "v1"},
+					{FieldPath: []string{"name"}, Pattern: "files/**"},
+				},
+				Variables: []*PathSegment{{FieldPath: []string{"name"}, Pattern: "files/**"}},
+			},
+		},
+		{
+			path: "/v1/{id}:cancel",
+			want: &PathTemplate{
+				Segments: []*PathSegment{
+					{Literal: "v1"},
+					{FieldPath: []string{"id"}},
+				},
+				Verb:      "cancel",
+				Variables: []*PathSegment{{FieldPath: []string{"id"}}},
+			},
+		},
+		{
+			path: "/v1/operations:batchGet",
+			want: &PathTemplate{
+				Segments: []*PathSegment{{Literal: "v1"}, {Literal: "operations"}},
+				Verb:     "batchGet",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePathTemplate(tt.path)
+		if err != nil {
+			t.Errorf("parsePathTemplate(%q) error: %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePathTemplate(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParsePathTemplateError(t *testing.T) {
+	paths := []string{
+		"v1/users",
+		"/v1/{id",
+		"/v1/id}",
+		"/v1/{}",
+		"/v1/{=projects/*}",
+		"/v1/{a{b}}",
+		"/v1/x{id}",
+	}
+
+	for _, path := range paths {
+		if _, err := parsePathTemplate(path); err == nil {
+			t.Errorf("parsePathTemplate(%q) want an error", path)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// wellKnownValue returns the sample value of google.protobuf well-known types in their protojson form,
+// false means message is not a well-known type
+func (p *Postman) wellKnownValue(message *protogen.Message, path []protoreflect.FullName) (interface{}, bool) {
+	switch message.Desc.FullName() {
+	case "google.protobuf.Timestamp":
+		return "1970-01-01T00:00:00Z", true
+	case "google.protobuf.Duration":
+		return "1.5s", true
+	case "google.protobuf.FieldMask":
+		return "a,b", true
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.Empty":
+		return NewObject(), true
+	case "google.protobuf.ListValue":
+		return []interface{}{}, true
+	case "google.protobuf.Any":
+		var anyObject = NewObject()
+		anyObject.Set("@type", "type.googleapis.com/google.protobuf.Empty")
+		return anyObject, true
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		// wrapper 类型直接使用其 value 字段的值
+		return p.singularValue(message.Fields[0], path), true
+	}
+
+	return nil, false
+}
+
+// wellKnownSchema returns the schema of google.protobuf well-known types in their protojson form,
+// false means message is not a well-known type
+func (p *Postman) wellKnownSchema(message *protogen.Message, defs *SchemaDefs) (*Schema, bool) {
+	switch message.Desc.FullName() {
+	case "google.protobuf.Timestamp":
+		return &Schema{Type: "string", Format: "date-time"}, true
+	case "google.protobuf.Duration":
+		// protojson 的 duration 是带 s 后缀的秒数，不是 ISO 8601 的 duration
+		return &Schema{Type: "string", Pattern: `^-?[0-9]+(\.[0-9]{1,9})?s$`}, true
+	case "google.protobuf.FieldMask":
+		return &Schema{Type: "string"}, true
+	case "google.protobuf.Struct", "google.protobuf.Empty":
+		return &Schema{Type: "object"}, true
+	case "google.protobuf.Value":
+		return &Schema{}, true
+	case "google.protobuf.ListValue":
+		return &Schema{Type: "array"}, true
+	case "google.protobuf.Any":
+		return &Schema{Type: "object", Required: []string{"@type"}}, true
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return p.singularSchema(message.Fields[0], defs), true
+	}
+
+	return nil, false
+}
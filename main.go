@@ -7,6 +7,8 @@ import (
 )
 
 func main() {
-	p := &internal.Postman{}
-	protogen.Options{}.Run(p.Generate)
+	p := internal.NewPostman()
+	protogen.Options{
+		ParamFunc: p.Set,
+	}.Run(p.Generate)
 }